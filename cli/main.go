@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"runtime/pprof"
 	"strings"
+	"time"
 
 	"github.com/c4pt0r/tcli"
 	"github.com/c4pt0r/tcli/client"
@@ -17,16 +20,31 @@ import (
 	"github.com/abiosoft/ishell"
 	"github.com/c4pt0r/log"
 	"github.com/fatih/color"
+	shlex "github.com/flynn-archive/go-shlex"
 	plog "github.com/pingcap/log"
+	"golang.org/x/term"
 )
 
 var (
-	pdAddr         = flag.String("pd", "localhost:2379", "PD addr")
+	pdAddr         = flag.String("pd", "localhost:2379", "PD addrs, separated by ','")
+	caPath         = flag.String("ca", "", "CA certificate path for TLS connections")
+	certPath       = flag.String("cert", "", "certificate path for TLS connections")
+	keyPath        = flag.String("key", "", "private key path for TLS connections")
+	certAllowedCN  = flag.String("cert-allowed-cn", "", "allowed common names of the certificates, separated by ','")
 	clientLog      = flag.String("log-file", "/dev/null", "TiKV client log file")
 	clientLogLevel = flag.String("log-level", "info", "TiKV client log level")
-	clientmode     = flag.String("mode", "txn", "TiKV API mode, accepted values: [raw | txn]")
+	clientmode     = flag.String("mode", "txn", "TiKV API mode, accepted values: [raw | txn | mem]")
+	apiVersion     = flag.Int("api-version", 1, "TiKV API version, accepted values: [1 | 2]")
+	keyspace       = flag.String("keyspace", "", "keyspace name, requires --api-version=2, default: the DEFAULT keyspace in API V2")
 	resultFmt      = flag.String("output-format", "table", "output format, accepted values: [table | json]")
-	profileFile    = flag.String("p", "", "profile file")
+	profileFile    = flag.String("p", "", "CPU profile file")
+	execStmts      = flag.String("e", "", "execute commands (separated by ';') and exit")
+	scriptFile     = flag.String("f", "", "execute commands in script file and exit")
+	continueOnErr  = flag.Bool("continue-on-error", false, "keep executing the rest commands when a command fails, only works in non-interactive mode")
+	quiet          = flag.Bool("q", false, "quiet mode, don't print the welcome message and the Success/Elapse banners")
+	configFile     = flag.String("config", utils.DefaultConfigPath(), "config file in TOML, or in YAML if its extension is .yaml or .yml")
+	profileName    = flag.String("profile", "", "profile in the config file, the flags set in the command line override the profile, default: the profile 'default' if there is")
+	historyFile    = flag.String("history-file", "", "history file of the interactive shell, default: ~/.tcli.history")
 )
 var (
 	logo string = ""
@@ -38,17 +56,25 @@ var RegisteredCmds = []tcli.Cmd{
 	kvcmds.ScanCmd{},
 	kvcmds.ScanPrefixCmd{},
 	kvcmds.HeadCmd{},
+	kvcmds.TailCmd{},
 	kvcmds.PutCmd{},
+	kvcmds.CasCmd{},
+	kvcmds.TTLCmd{},
 	kvcmds.BackupCmd{},
+	kvcmds.RestoreCmd{},
+	kvcmds.CopyCmd{},
+	kvcmds.DiffCmd{},
 	kvcmds.NewBenchCmd(
 		kvcmds.NewYcsbBench(*pdAddr),
 	),
 	kvcmds.GetCmd{},
+	kvcmds.MgetCmd{},
 	kvcmds.LoadCsvCmd{},
 	kvcmds.DeleteCmd{},
 	kvcmds.DeletePrefixCmd{},
 	kvcmds.DeleteAllCmd{},
 	kvcmds.CountCmd{},
+	kvcmds.ChecksumCmd{},
 	kvcmds.EchoCmd{},
 	kvcmds.HexCmd{},
 	kvcmds.VarCmd{},
@@ -57,9 +83,16 @@ var RegisteredCmds = []tcli.Cmd{
 	kvcmds.SysVarCmd{},
 	kvcmds.ExplainCmd{},
 	kvcmds.QueryCmd{},
+	kvcmds.BeginCmd{},
+	kvcmds.CommitCmd{},
+	kvcmds.RollbackCmd{},
 	opcmds.ListStoresCmd{},
 	opcmds.ListPDCmd{},
-	//opcmds.ConnectCmd{},
+	opcmds.RegionCmd{},
+	opcmds.ListRegionsCmd{},
+	opcmds.ListKeyspacesCmd{},
+	opcmds.ConnectCmd{},
+	opcmds.DisconnectCmd{},
 	//opcmds.ConfigEditorCmd{},
 }
 
@@ -73,19 +106,27 @@ func initLog() {
 }
 
 func showWelcomeMessage() {
-	fmt.Fprintf(
-		os.Stderr,
-		"Welcome, TiKV Cluster ID: %s, TiKV Mode: %s\n",
-		client.GetTiKVClient().GetClusterID(),
-		client.GetTiKVClient().GetClientMode(),
-	)
-
-	if client.GetTiKVClient().GetClientMode() == client.RAW_CLIENT {
+	if !utils.IsQuiet() {
+		var ks string
+		if name := client.ClientAPI(client.GetTiKVClient()).KeyspaceName(); name != "" {
+			ks = ", Keyspace: " + name
+		}
+		fmt.Fprintf(
+			os.Stderr,
+			"Welcome, TiKV Cluster ID: %s, TiKV Mode: %s%s\n",
+			client.GetTiKVClient().GetClusterID(),
+			client.GetTiKVClient().GetClientMode(),
+			ks,
+		)
+	}
+
+	// mem mode has no PD
+	pdClient := client.GetTiKVClient().GetPDClient()
+	if pdClient == nil {
 		return
 	}
 
 	// show pd members
-	pdClient := client.GetTiKVClient().GetPDClient()
 	members, err := pdClient.GetAllMembers(context.TODO())
 	if err != nil {
 		log.F(err)
@@ -102,18 +143,281 @@ func showWelcomeMessage() {
 	}
 }
 
+// cmdFunc wraps the handler of cmd into an ishell command function
+func cmdFunc(cmd tcli.Cmd) func(c *ishell.Context) {
+	handler := cmd.Handler()
+	//completer := cmd.Completer()
+	longhelp := cmd.LongHelp()
+	return func(c *ishell.Context) {
+		ctx := context.WithValue(context.TODO(), "ishell", c)
+		if strings.ToLower(*clientLogLevel) == "debug" {
+			fmt.Fprintln(os.Stderr, color.YellowString("Input:"), c.RawArgs)
+			for _, arg := range c.Args {
+				fmt.Fprintln(os.Stderr, color.YellowString("Arg:"), arg)
+			}
+			fmt.Fprintf(os.Stderr, "\033[33mOutput:\033[0m\n")
+		}
+		if len(c.Args) > 0 && c.Args[0] == "--help" {
+			utils.Print(longhelp)
+			return
+		}
+		handler(ctx)
+		// refresh the prompt, the command may have opened or closed a transaction
+		if c.Actions != nil {
+			c.SetPrompt(prompt())
+		}
+	}
+}
+
+// prompt returns the shell prompt, showing the open transaction if there is
+func prompt() string {
+	kvClient := client.GetTiKVClient()
+	var p string
+	if pdClient := kvClient.GetPDClient(); pdClient == nil {
+		p = fmt.Sprint(kvClient.GetClientMode())
+	} else {
+		p = fmt.Sprintf("%s @ %s", kvClient.GetClientMode(), pdClient.GetLeaderURL())
+	}
+	if name := client.ClientAPI(kvClient).KeyspaceName(); name != "" {
+		p += fmt.Sprintf(" [keyspace %s]", name)
+	}
+	if info := kvClient.GetTxnInfo(); info != nil {
+		p += fmt.Sprintf(" [txn %s]", time.Since(info.StartTime).Round(time.Second))
+	}
+	return p + "> "
+}
+
+// rollbackOpenTxn rolls back the transaction left open on exit
+func rollbackOpenTxn() {
+	if client.GetTiKVClient().GetTxnInfo() == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, color.YellowString("Warning: the open transaction is rolled back"))
+	if err := client.GetTiKVClient().Rollback(context.TODO()); err != nil {
+		fmt.Fprintf(os.Stderr, "\033[31mError: %s\033[0m\n", err)
+	}
+}
+
+// cmdsByName returns the registered commands by their names and aliases
+func cmdsByName() map[string]tcli.Cmd {
+	cmds := make(map[string]tcli.Cmd)
+	for _, cmd := range RegisteredCmds {
+		cmds[cmd.Name()] = cmd
+	}
+	for _, cmd := range RegisteredCmds {
+		for _, alias := range cmd.Alias() {
+			if _, ok := cmds[alias]; !ok {
+				cmds[alias] = cmd
+			}
+		}
+	}
+	return cmds
+}
+
+// profileAlias is a command alias defined in the profile
+type profileAlias struct {
+	command string
+	cmd     tcli.Cmd
+	args    []string
+	rawArgs []string
+}
+
+// expand returns the args and the raw args of the aliased command, the args
+// of the alias are appended to the ones of the command
+func (a *profileAlias) expand(args, rawArgs []string) ([]string, []string) {
+	retArgs := append(append([]string{}, a.args...), args...)
+	retRawArgs := append([]string{}, a.rawArgs...)
+	if len(rawArgs) > 1 { // rawArgs[0] is the alias name
+		retRawArgs = append(retRawArgs, rawArgs[1:]...)
+	}
+	return retArgs, retRawArgs
+}
+
+// profileAliases are the command aliases of the profile, by their names
+var profileAliases = make(map[string]*profileAlias)
+
+func loadProfileAliases(aliases map[string]string) error {
+	cmds := cmdsByName()
+	for name, command := range aliases {
+		if _, ok := cmds[name]; ok || name == "exit" || name == "quit" {
+			return fmt.Errorf("alias %s conflicts with a command name", name)
+		}
+		args, err := shlex.Split(command)
+		if err != nil {
+			return fmt.Errorf("alias %s: %s", name, err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("alias %s: empty command", name)
+		}
+		cmd, ok := cmds[args[0]]
+		if !ok {
+			return fmt.Errorf("alias %s: unknown command: %s", name, args[0])
+		}
+		profileAliases[name] = &profileAlias{
+			command: command,
+			cmd:     cmd,
+			args:    args[1:],
+			rawArgs: strings.Fields(command),
+		}
+	}
+	return nil
+}
+
+// isFlagSet returns true if the flag is set in the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// applyProfile uses the settings of the profile as the defaults of the flags,
+// the flags set in the command line take precedence
+func applyProfile(cfg *utils.Config) error {
+	name := *profileName
+	p, ok := cfg.GetProfile(name)
+	if name == "" {
+		name = utils.DefaultProfileName
+		if p, ok = cfg.GetProfile(name); !ok {
+			return nil
+		}
+	} else if !ok {
+		return fmt.Errorf("profile %s is not found in the config file %s", name, *configFile)
+	}
+
+	override := func(flagName string, dst *string, val string) {
+		if !isFlagSet(flagName) && val != "" {
+			*dst = val
+		}
+	}
+	override("pd", pdAddr, p.PD)
+	override("mode", clientmode, p.Mode)
+	override("ca", caPath, p.CA)
+	override("cert", certPath, p.Cert)
+	override("key", keyPath, p.Key)
+	override("cert-allowed-cn", certAllowedCN, strings.Join(p.CertAllowedCN, ","))
+	override("keyspace", keyspace, p.Keyspace)
+	if !isFlagSet("api-version") && p.APIVersion != 0 {
+		*apiVersion = p.APIVersion
+	}
+
+	for k, v := range p.SysVars {
+		utils.SysVarSet(k, v)
+	}
+	if p.OutputFormat != "" {
+		utils.SysVarSet(utils.SysVarPrintFormatKey, p.OutputFormat)
+	}
+	return loadProfileAliases(p.Aliases)
+}
+
+// runScript reads statements from r line by line and executes them without
+// the interactive shell, lines starting with '#' are comments.
+// returns false if any of the statements failed
+func runScript(r io.Reader) bool {
+	cmds := cmdsByName()
+
+	// the transaction left open by the script is never committed
+	defer rollbackOpenTxn()
+
+	succ := true
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, stmt := range utils.SplitStatements(line) {
+			// same as ishell: Args are shell-like split, RawArgs keep the quotes
+			args, err := shlex.Split(stmt)
+			if err == nil && len(args) == 0 {
+				continue
+			}
+			if err == nil && (args[0] == "exit" || args[0] == "quit") {
+				return succ
+			}
+			utils.ResetLastCmdError()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\033[31mError: %s\033[0m\n", err)
+			} else if cmd, ok := cmds[args[0]]; ok {
+				cmdFunc(cmd)(&ishell.Context{
+					Args:    args[1:],
+					RawArgs: strings.Fields(stmt),
+				})
+				err = utils.LastCmdError()
+			} else if alias, ok := profileAliases[args[0]]; ok {
+				ctx := &ishell.Context{}
+				ctx.Args, ctx.RawArgs = alias.expand(args[1:], strings.Fields(stmt))
+				cmdFunc(alias.cmd)(ctx)
+				err = utils.LastCmdError()
+			} else {
+				err = fmt.Errorf("unknown command: %s", args[0])
+				fmt.Fprintf(os.Stderr, "\033[31mError: %s\033[0m\n", err)
+			}
+			if err != nil {
+				succ = false
+				if !*continueOnErr {
+					return succ
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "\033[31mError: %s\033[0m\n", err)
+		return false
+	}
+	return succ
+}
+
 func main() {
 	flag.Parse()
 	initLog()
-	fmt.Fprintf(os.Stderr, "Try connecting to PD: %s...", *pdAddr)
-	if err := client.InitTiKVClient([]string{*pdAddr}, *clientmode); err != nil {
+	utils.InitBuiltinVaribles()
+
+	cfg, err := utils.LoadConfig(*configFile)
+	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Fprintf(os.Stderr, "done\n")
-	utils.InitBuiltinVaribles()
+	utils.SetConfig(cfg)
+	if err := applyProfile(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if *historyFile != "" {
+		cfg.HistoryFile = *historyFile
+	}
+
+	// Set output format, the default one is set by InitBuiltinVaribles or
+	// the profile
+	if isFlagSet("output-format") {
+		utils.SysVarSet(utils.SysVarPrintFormatKey, *resultFmt)
+	}
+	if *quiet {
+		utils.SysVarSet(utils.SysVarQuietKey, "true")
+	}
 
-	// Set output format
-	utils.SysVarSet(utils.SysVarPrintFormatKey, *resultFmt)
+	if !utils.IsQuiet() {
+		if *clientmode == "mem" {
+			fmt.Fprintf(os.Stderr, "Using in-memory storage, nothing will be persisted...")
+		} else {
+			fmt.Fprintf(os.Stderr, "Try connecting to PD: %s...", *pdAddr)
+		}
+	}
+	security := client.SecurityOption{
+		CAPath:        *caPath,
+		CertPath:      *certPath,
+		KeyPath:       *keyPath,
+		CertAllowedCN: utils.SplitList(*certAllowedCN),
+	}
+	api := client.APIOption{Version: *apiVersion, Keyspace: *keyspace}
+	if err := client.InitTiKVClient(utils.SplitList(*pdAddr), *clientmode, security, api); err != nil {
+		log.Fatal(err)
+	}
+	if !utils.IsQuiet() {
+		fmt.Fprintf(os.Stderr, "done\n")
+	}
 
 	showWelcomeMessage()
 
@@ -129,46 +433,76 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	// non-interactive mode, read commands from -e, -f or piped stdin
+	var script io.Reader
+	if *execStmts != "" {
+		script = strings.NewReader(*execStmts)
+	} else if *scriptFile != "" {
+		fp, err := os.Open(*scriptFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer fp.Close()
+		script = fp
+	} else if !term.IsTerminal(int(os.Stdin.Fd())) {
+		script = os.Stdin
+	}
+	if script != nil {
+		// nobody answers the confirmations, the commands need --yes
+		utils.SetInteractive(false)
+		if !runScript(script) {
+			pprof.StopCPUProfile()
+			os.Exit(1)
+		}
+		return
+	}
+
 	// set shell prompts
 	shell := ishell.New()
-	if client.GetTiKVClient().GetClientMode() == client.RAW_CLIENT {
-		// TODO: add pd leader addr after we can get PD client from RawKV client.
-		shell.SetPrompt(fmt.Sprintf("%s> ", client.GetTiKVClient().GetClientMode()))
-	} else {
-		pdLeaderAddr := client.GetTiKVClient().GetPDClient().GetLeaderAddr()
-		shell.SetPrompt(fmt.Sprintf("%s @ %s> ", client.GetTiKVClient().GetClientMode(), pdLeaderAddr))
+	shell.SetPrompt(prompt())
+	// warn before leaving with an open transaction, exit again to roll it back
+	var warnedTS uint64
+	exit := func(c *ishell.Context) {
+		if info := client.GetTiKVClient().GetTxnInfo(); info != nil && info.StartTS != warnedTS {
+			warnedTS = info.StartTS
+			fmt.Fprintf(os.Stderr, color.YellowString("Warning: transaction %d is still open, commit or rollback it, or exit again to roll it back\n"), info.StartTS)
+			return
+		}
+		rollbackOpenTxn()
+		c.Stop()
 	}
-	shell.EOF(func(c *ishell.Context) { shell.Close() })
+	shell.EOF(exit)
 	shell.AutoHelp(false)
 
 	// register shell commands
+	shell.SetHistoryPath(cfg.HistoryPath())
 	for _, cmd := range RegisteredCmds {
-		handler := cmd.Handler()
-		//completer := cmd.Completer()
-		longhelp := cmd.LongHelp()
-		shell.SetHomeHistoryPath(".tcli.history")
 		shell.AddCmd(&ishell.Cmd{
 			Name:     cmd.Name(),
 			Help:     cmd.Help(),
 			LongHelp: cmd.LongHelp(),
 			Aliases:  cmd.Alias(),
+			Func:     cmdFunc(cmd),
+		})
+	}
+	for name, alias := range profileAliases {
+		alias := alias
+		f := cmdFunc(alias.cmd)
+		shell.AddCmd(&ishell.Cmd{
+			Name:     name,
+			Help:     "alias of " + alias.command,
+			LongHelp: "alias of " + alias.command + "\n" + alias.cmd.LongHelp(),
 			Func: func(c *ishell.Context) {
-				ctx := context.WithValue(context.TODO(), "ishell", c)
-				if strings.ToLower(*clientLogLevel) == "debug" {
-					fmt.Fprintln(os.Stderr, color.YellowString("Input:"), c.RawArgs)
-					for _, arg := range c.Args {
-						fmt.Fprintln(os.Stderr, color.YellowString("Arg:"), arg)
-					}
-					fmt.Fprintf(os.Stderr, "\033[33mOutput:\033[0m\n")
-				}
-				if len(c.Args) > 0 && c.Args[0] == "--help" {
-					c.Println(longhelp)
-					return
-				}
-				handler(ctx)
+				c.Args, c.RawArgs = alias.expand(c.Args, c.RawArgs)
+				f(c)
 			},
 		})
 	}
+	shell.AddCmd(&ishell.Cmd{
+		Name: "exit",
+		Help: "exit the program",
+		Func: exit,
+	})
 	shell.Run()
 	shell.Close()
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+)
+
+func TestRunScript(t *testing.T) {
+	utils.InitBuiltinVaribles()
+	utils.SysVarSet(utils.SysVarQuietKey, "true")
+	utils.SetInteractive(false)
+	defer utils.SetInteractive(true)
+	if err := loadProfileAliases(map[string]string{"seta": "put a"}); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(profileAliases, "seta")
+
+	cases := []struct {
+		name      string
+		script    string
+		succ      bool
+		contOnErr bool
+		// the value of key a after the script, empty means not exists
+		wantA string
+	}{
+		{"statements", "put a 1; put b 2\nput a 3", true, false, "3"},
+		{"comments", "# put a 1\n  # put a 2\nput a 3", true, false, "3"},
+		{"quoted separator", `put a "1;2"`, true, false, "1;2"},
+		{"empty lines", "\n\n;;put a 1;\n", true, false, "1"},
+		{"alias", "seta 5", true, false, "5"},
+		{"exit", "put a 1; exit; put a 2", true, false, "1"},
+		{"quit", "put a 1\nquit\nput a 2", true, false, "1"},
+		{"unknown command", "put a 1; foo; put a 2", false, false, "1"},
+		{"continue on error", "put a 1; foo; put a 2", false, true, "2"},
+		{"failed command", "put a 1; get a --as-of=1; put a 2", false, false, "1"},
+		{"unterminated quote", "put a 1\nput a \"2", false, false, "1"},
+		{"confirmation", "put a 1; delp a", false, false, "1"},
+		{"confirmed", "put a 1; delp a --yes", true, false, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := client.InitTiKVClient(nil, "mem", client.SecurityOption{}, client.APIOption{}); err != nil {
+				t.Fatal(err)
+			}
+			*continueOnErr = tc.contOnErr
+			defer func() { *continueOnErr = false }()
+
+			if succ := runScript(strings.NewReader(tc.script)); succ != tc.succ {
+				t.Fatalf("got %v, want %v", succ, tc.succ)
+			}
+			kv, err := client.GetTiKVClient().Get(context.TODO(), client.Key("a"))
+			if tc.wantA == "" {
+				if err == nil {
+					t.Fatalf("got a = %q, want not exists", kv.V)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(kv.V) != tc.wantA {
+				t.Fatalf("got a = %q, want %q", kv.V, tc.wantA)
+			}
+		})
+	}
+}
+
+func TestProfileAliases(t *testing.T) {
+	cases := []struct {
+		name    string
+		aliases map[string]string
+		wantErr string
+	}{
+		{"valid", map[string]string{"ls": "scanp", "first10": "scan $head --limit=10"}, ""},
+		{"command name", map[string]string{"get": "scanp"}, "conflicts with a command name"},
+		{"exit", map[string]string{"exit": "scanp"}, "conflicts with a command name"},
+		{"empty", map[string]string{"x": " "}, "empty command"},
+		{"unknown command", map[string]string{"x": "foo"}, "unknown command: foo"},
+		{"invalid quote", map[string]string{"x": "get \"a"}, "alias x"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				for name := range tc.aliases {
+					delete(profileAliases, name)
+				}
+			}()
+			err := loadProfileAliases(tc.aliases)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+
+	// the args of the alias are appended to the command
+	alias := &profileAlias{args: []string{"$head"}, rawArgs: []string{"scan", "$head"}}
+	args, rawArgs := alias.expand([]string{"--limit=10"}, []string{"first", "--limit=10"})
+	if want := []string{"$head", "--limit=10"}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("got args %q, want %q", args, want)
+	}
+	if want := []string{"scan", "$head", "--limit=10"}; !reflect.DeepEqual(rawArgs, want) {
+		t.Fatalf("got raw args %q, want %q", rawArgs, want)
+	}
+}
@@ -1,16 +1,24 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/c4pt0r/tcli"
 	"github.com/c4pt0r/tcli/utils"
 
+	"github.com/magiconair/properties"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pkg/errors"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/oracle"
 	pd "github.com/tikv/pd/client"
 )
 
@@ -62,6 +70,9 @@ func (kvs KVS) Print() {
 				data = append(data, row)
 			}
 			utils.PrintTable(data)
+			if utils.IsQuiet() {
+				return
+			}
 			if len(kvs) > 1 {
 				fmt.Fprintf(os.Stderr, "%d Records Found\n", len(kvs))
 			} else {
@@ -77,28 +88,76 @@ var (
 	_globalKvClient atomic.Value
 )
 
-func InitTiKVClient(pdAddrs []string, clientMode string) error {
+// kvClientHolder keeps the type stored in _globalKvClient consistent, so that
+// clients of different modes can be swapped
+type kvClientHolder struct {
+	Client
+}
+
+// NewClient creates a client of clientMode connected to the PD cluster
+func NewClient(pdAddrs []string, clientMode string, security SecurityOption, api APIOption) (Client, error) {
+	var (
+		kvClient Client
+		err      error
+	)
+	if err := security.Validate(); err != nil {
+		return nil, err
+	}
+	if err := api.Validate(); err != nil {
+		return nil, err
+	}
 	switch strings.ToLower(clientMode) {
 	case "raw":
-		kvClient := newRawKVClient(pdAddrs)
-		_globalKvClient.Store(kvClient)
-		return nil
+		kvClient, err = newRawKVClient(pdAddrs, security, api)
 	case "txn":
-		kvClient := newTxnKVClient(pdAddrs)
-		_globalKvClient.Store(kvClient)
-		return nil
+		kvClient, err = newTxnKVClient(pdAddrs, security, api)
+	case "mem":
+		kvClient = newMemKVClient()
 	default:
-		return errors.Errorf("Unrecognized TiKV mode: %s", clientMode)
+		return nil, errors.Errorf("Unrecognized TiKV mode: %s", clientMode)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return kvClient, nil
+}
+
+// InitTiKVClient creates the global client, the security and the API options
+// are also the default ones of the clients created later
+func InitTiKVClient(pdAddrs []string, clientMode string, security SecurityOption, api APIOption) error {
+	kvClient, err := NewClient(pdAddrs, clientMode, security, api)
+	if err != nil {
+		return err
+	}
+	SetDefaultSecurity(security)
+	SetDefaultAPI(api)
+	_globalKvClient.Store(kvClientHolder{kvClient})
+	return nil
 }
 
 func GetTiKVClient() Client {
-	return _globalKvClient.Load().(Client)
+	return _globalKvClient.Load().(kvClientHolder).Client
+}
+
+// SwitchTiKVClient replaces the global client with c, and closes the old one
+func SwitchTiKVClient(c Client) {
+	old := _globalKvClient.Swap(kvClientHolder{c})
+	if old != nil {
+		old.(kvClientHolder).Close()
+	}
+}
+
+// DisconnectTiKVClient closes the global client, the operations fail with
+// ErrNotConnected until the client is switched by SwitchTiKVClient
+func DisconnectTiKVClient() {
+	SwitchTiKVClient(newDisconnectedClient())
 }
 
 // Make sure txnkvClient implements Client interface
 var _ Client = (*txnkvClient)(nil)
 var _ Client = (*rawkvClient)(nil)
+var _ Client = (*memkvClient)(nil)
+var _ Client = (*disconnectedClient)(nil)
 
 type Client interface {
 	GetClientMode() TiKV_MODE
@@ -107,22 +166,65 @@ type Client interface {
 	GetPDs() ([]PDInfo, error)
 	GetPDClient() pd.Client
 
+	// puts in raw mode accept the TTL option (tcli.PutOptTTL) in ctx
 	Put(ctx context.Context, kv KV) error
 	BatchPut(ctx context.Context, kv []KV) error
 
 	Get(ctx context.Context, k Key) (KV, error)
+	// BatchGet returns the existing kv pairs of keys, in the order of keys
+	BatchGet(ctx context.Context, keys []Key) (KVS, error)
 	Scan(ctx context.Context, prefix []byte) (KVS, int, error)
 
 	Delete(ctx context.Context, k Key) error
 	BatchDelete(ctx context.Context, kvs []KV) error
 	DeletePrefix(ctx context.Context, prefix Key, limit int) (Key, int, error)
+
+	// GetTTL returns the remaining TTL of k, 0 means k never expires
+	GetTTL(ctx context.Context, k Key) (time.Duration, error)
+
+	// CompareAndSwap sets k to newValue if its current value equals oldValue,
+	// a nil oldValue means k must not exist. It returns the value before the
+	// swap (nil if k didn't exist) and whether the swap happened.
+	CompareAndSwap(ctx context.Context, k Key, oldValue, newValue []byte) ([]byte, bool, error)
+
+	// interactive transaction, only supported in txn mode.
+	// once begun, the read and write methods above run in the open transaction.
+	Begin(ctx context.Context, pessimistic bool) error
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+	// GetTxnInfo returns nil if there's no open transaction
+	GetTxnInfo() *TxnInfo
+
+	// Close releases the connections to the cluster
+	Close()
 }
 
+// TxnInfo describes the open interactive transaction
+type TxnInfo struct {
+	StartTS     uint64
+	StartTime   time.Time
+	Pessimistic bool
+}
+
+// casMatch checks the current value (nil if not exists) against the expected
+// value of CompareAndSwap
+func casMatch(cur, expected []byte) bool {
+	if expected == nil || cur == nil {
+		return expected == nil && cur == nil
+	}
+	return bytes.Equal(cur, expected)
+}
+
+var errTxnNotSupported = errors.New("transactions are only supported in txn mode")
+
 type TiKV_MODE int
 
 const (
 	RAW_CLIENT TiKV_MODE = 0
 	TXN_CLIENT TiKV_MODE = 1
+	MEM_CLIENT TiKV_MODE = 2
+	// the client after .disconnect
+	NONE_CLIENT TiKV_MODE = 3
 )
 
 func (mode TiKV_MODE) String() string {
@@ -131,6 +233,10 @@ func (mode TiKV_MODE) String() string {
 		return "Mode: Raw"
 	case TXN_CLIENT:
 		return "Mode: Txn"
+	case MEM_CLIENT:
+		return "Mode: Mem"
+	case NONE_CLIENT:
+		return "Mode: Disconnected"
 	}
 	return "unknown"
 }
@@ -139,6 +245,132 @@ var (
 	propertiesKey = "property"
 )
 
+// AsOfLatest is the --as-of value of reading the latest version, it overrides
+// the sys.read_ts system variable
+const AsOfLatest = "latest"
+
+// LatestReadOpt returns a copy of opt which reads the latest version, it's
+// used by the reads for writing and the reads of another cluster, which never
+// see the snapshot of --as-of or sys.read_ts
+func LatestReadOpt(opt *properties.Properties) *properties.Properties {
+	ret := properties.NewProperties()
+	if opt != nil {
+		ret.Merge(opt)
+	}
+	ret.Set(tcli.ScanOptAsOf, AsOfLatest)
+	return ret
+}
+
+// getReadTS returns the timestamp of snapshot reads, the --as-of option in
+// ctx takes precedence over the sys.read_ts system variable.
+// returns 0 if neither is set or it's AsOfLatest, which means reading the
+// latest version.
+func getReadTS(ctx context.Context) (uint64, error) {
+	readTS := utils.PropFromContext(ctx).GetString(tcli.ScanOptAsOf, "")
+	if len(readTS) == 0 {
+		readTS, _ = utils.SysVarGet(utils.SysVarReadTSKey)
+	}
+	if len(readTS) == 0 || readTS == AsOfLatest {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseUint(readTS, 10, 64); err == nil {
+		return ts, nil
+	}
+	t, err := time.Parse(time.RFC3339, readTS)
+	if err != nil {
+		return 0, fmt.Errorf("invalid read timestamp: %s, should be a TSO or a RFC3339 time", readTS)
+	}
+	return oracle.GoTimeToTS(t), nil
+}
+
+// checkNoReadTS returns an error if a snapshot read is required, it's used
+// by the clients without MVCC support.
+func checkNoReadTS(ctx context.Context) error {
+	ts, err := getReadTS(ctx)
+	if err != nil {
+		return err
+	}
+	if ts != 0 {
+		return errors.New("snapshot read (--as-of or sys.read_ts) is only supported in txn mode")
+	}
+	return nil
+}
+
+// getTTL returns the TTL of puts set by the --ttl option in ctx, 0 means the
+// keys never expire. TiKV takes TTL in seconds, so it's rounded up.
+func getTTL(ctx context.Context) (uint64, error) {
+	s := utils.PropFromContext(ctx).GetString(tcli.PutOptTTL, "")
+	if len(s) == 0 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid ttl: %s, should be a positive duration like 30s, 10m or 1h", s)
+	}
+	return uint64((d + time.Second - 1) / time.Second), nil
+}
+
+// checkNoTTL returns an error if TTL is required, it's used by the clients
+// without TTL support.
+func checkNoTTL(ctx context.Context) error {
+	ttl, err := getTTL(ctx)
+	if err != nil {
+		return err
+	}
+	if ttl != 0 {
+		return errors.New("ttl (--ttl) is only supported in raw mode")
+	}
+	return nil
+}
+
+// retryableErrors are the transient errors of TiKV, which may succeed on retry
+var retryableErrors = []error{
+	tikverr.ErrTiKVServerTimeout,
+	tikverr.ErrTiKVServerBusy,
+	tikverr.ErrTiKVStaleCommand,
+	tikverr.ErrRegionUnavailable,
+	tikverr.ErrRegionDataNotReady,
+	tikverr.ErrRegionNotInitialized,
+	tikverr.ErrResolveLockTimeout,
+	tikverr.ErrLockWaitTimeout,
+}
+
+// IsRetryableError returns whether the write failed with err can be retried,
+// like write conflicts and region errors.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if tikverr.IsErrWriteConflict(err) {
+		return true
+	}
+	cause := errors.Cause(err)
+	for _, e := range retryableErrors {
+		if cause == e {
+			return true
+		}
+	}
+	return false
+}
+
+// reverseScanMaxKey is used as the upper bound of a reverse scan which has
+// no end key, locating the last region by an empty key is not supported.
+var reverseScanMaxKey = bytes.Repeat([]byte{0xff}, 64)
+
+// reverseScanUpperBound returns the exclusive upper bound of a reverse scan
+// which starts from startKey.
+func reverseScanUpperBound(startKey, endKey []byte, strictPrefix bool) []byte {
+	if len(endKey) > 0 {
+		return endKey
+	}
+	if strictPrefix {
+		if end := utils.PrefixEnd(startKey); end != nil {
+			return end
+		}
+	}
+	return reverseScanMaxKey
+}
+
 type StoreInfo struct {
 	ID            string
 	Version       string
@@ -153,6 +385,44 @@ type PDInfo struct {
 	ClientURLs []string
 }
 
+// getStores lists the stores by pdClient, it's shared by the txn and raw clients
+func getStores(pdClient pd.Client) ([]StoreInfo, error) {
+	var ret []StoreInfo
+	stores, err := pdClient.GetAllStores(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	for _, store := range stores {
+		labels := store.GetLabels()
+		var strLabels []string
+		for _, label := range labels {
+			strLabels = append(strLabels, fmt.Sprintf("%s=%s", label.Key, label.Value))
+		}
+		ret = append(ret, StoreInfo{
+			ID:            fmt.Sprintf("%d", store.GetId()),
+			Version:       store.GetVersion(),
+			Addr:          store.GetAddress(),
+			State:         store.GetState().String(),
+			StatusAddress: store.GetStatusAddress(),
+			Labels:        strings.Join(strLabels, ","),
+		})
+	}
+	return ret, nil
+}
+
+// getPDs lists the PD members by pdClient, it's shared by the txn and raw clients
+func getPDs(pdClient pd.Client) ([]PDInfo, error) {
+	pds, err := pdClient.GetAllMembers(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	var ret []PDInfo
+	for _, pd := range pds {
+		ret = append(ret, PDInfo{Name: pd.Name, ClientURLs: pd.GetClientUrls()})
+	}
+	return ret, nil
+}
+
 func (StoreInfo) TableTitle() []string {
 	return []string{"Store ID", "Version", "Address", "State", "Status Address", "Labels"}
 }
@@ -166,6 +436,59 @@ func (s StoreInfo) String() string {
 		s.ID, s.Version, s.Addr, s.State, s.StatusAddress, s.Labels)
 }
 
+// RegionInfo is a region located by PD, the keys are in string literal
+type RegionInfo struct {
+	ID       string
+	StartKey string
+	EndKey   string
+	Epoch    string
+	Leader   string
+	Peers    string
+}
+
+// NewRegionInfo flattens the region, storeAddrs maps the store IDs to their
+// addresses, which are shown with the leader if found
+func NewRegionInfo(region *pd.Region, storeAddrs map[uint64]string) RegionInfo {
+	meta := region.Meta
+	info := RegionInfo{
+		ID:       fmt.Sprintf("%d", meta.GetId()),
+		StartKey: utils.Bytes2StrLit(meta.GetStartKey()),
+		EndKey:   utils.Bytes2StrLit(meta.GetEndKey()),
+		Epoch: fmt.Sprintf("conf_ver:%d version:%d",
+			meta.GetRegionEpoch().GetConfVer(), meta.GetRegionEpoch().GetVersion()),
+	}
+	if region.Leader != nil {
+		storeID := region.Leader.GetStoreId()
+		info.Leader = fmt.Sprintf("peer %d on store %d", region.Leader.GetId(), storeID)
+		if addr, ok := storeAddrs[storeID]; ok {
+			info.Leader += fmt.Sprintf(" (%s)", addr)
+		}
+	}
+	var peers []string
+	for _, peer := range meta.GetPeers() {
+		p := fmt.Sprintf("%d@store%d", peer.GetId(), peer.GetStoreId())
+		if peer.GetRole() != metapb.PeerRole_Voter {
+			p += fmt.Sprintf("(%s)", strings.ToLower(peer.GetRole().String()))
+		}
+		peers = append(peers, p)
+	}
+	info.Peers = strings.Join(peers, ",")
+	return info
+}
+
+func (RegionInfo) TableTitle() []string {
+	return []string{"Region ID", "Start Key", "End Key", "Epoch", "Leader", "Peers"}
+}
+
+func (r RegionInfo) Flatten() []string {
+	return []string{r.ID, r.StartKey, r.EndKey, r.Epoch, r.Leader, r.Peers}
+}
+
+func (r RegionInfo) String() string {
+	return fmt.Sprintf("region_id:\"%s\" start_key:\"%s\" end_key:\"%s\" epoch:\"%s\" leader:\"%s\" peers:\"%s\"",
+		r.ID, r.StartKey, r.EndKey, r.Epoch, r.Leader, r.Peers)
+}
+
 func (p PDInfo) TableTitle() []string {
 	return []string{"Name", "Client URLs"}
 }
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pd "github.com/tikv/pd/client"
+)
+
+// ErrNotConnected is returned by all the operations after .disconnect
+var ErrNotConnected = errors.New("not connected, use .connect to connect to a cluster")
+
+// disconnectedClient replaces the global client after .disconnect, so the
+// commands fail with ErrNotConnected until .connect is used.
+type disconnectedClient struct{}
+
+func newDisconnectedClient() *disconnectedClient {
+	return &disconnectedClient{}
+}
+
+func (c *disconnectedClient) Close() {}
+
+func (c *disconnectedClient) GetClientMode() TiKV_MODE {
+	return NONE_CLIENT
+}
+
+func (c *disconnectedClient) GetClusterID() string {
+	return "none"
+}
+
+func (c *disconnectedClient) GetStores() ([]StoreInfo, error) {
+	return nil, ErrNotConnected
+}
+
+func (c *disconnectedClient) GetPDs() ([]PDInfo, error) {
+	return nil, ErrNotConnected
+}
+
+func (c *disconnectedClient) GetPDClient() pd.Client {
+	return nil
+}
+
+func (c *disconnectedClient) Put(ctx context.Context, kv KV) error {
+	return ErrNotConnected
+}
+
+func (c *disconnectedClient) BatchPut(ctx context.Context, kvs []KV) error {
+	return ErrNotConnected
+}
+
+func (c *disconnectedClient) Get(ctx context.Context, k Key) (KV, error) {
+	return KV{}, ErrNotConnected
+}
+
+func (c *disconnectedClient) BatchGet(ctx context.Context, keys []Key) (KVS, error) {
+	return nil, ErrNotConnected
+}
+
+func (c *disconnectedClient) Scan(ctx context.Context, prefix []byte) (KVS, int, error) {
+	return nil, 0, ErrNotConnected
+}
+
+func (c *disconnectedClient) Delete(ctx context.Context, k Key) error {
+	return ErrNotConnected
+}
+
+func (c *disconnectedClient) BatchDelete(ctx context.Context, kvs []KV) error {
+	return ErrNotConnected
+}
+
+func (c *disconnectedClient) DeletePrefix(ctx context.Context, prefix Key, limit int) (Key, int, error) {
+	return nil, 0, ErrNotConnected
+}
+
+func (c *disconnectedClient) GetTTL(ctx context.Context, k Key) (time.Duration, error) {
+	return 0, ErrNotConnected
+}
+
+func (c *disconnectedClient) CompareAndSwap(ctx context.Context, k Key, oldValue, newValue []byte) ([]byte, bool, error) {
+	return nil, false, ErrNotConnected
+}
+
+func (c *disconnectedClient) Begin(ctx context.Context, pessimistic bool) error {
+	return ErrNotConnected
+}
+
+func (c *disconnectedClient) Commit(ctx context.Context) error {
+	return ErrNotConnected
+}
+
+func (c *disconnectedClient) Rollback(ctx context.Context) error {
+	return ErrNotConnected
+}
+
+func (c *disconnectedClient) GetTxnInfo() *TxnInfo {
+	return nil
+}
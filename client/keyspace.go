@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/pkg/errors"
+)
+
+// APIOption is the TiKV API version of the requests and the keyspace they
+// access, the keyspace requires API V2
+type APIOption struct {
+	// 1 or 2, 0 is the same as 1
+	Version int
+	// the keyspace name, empty means the default keyspace in API V2
+	Keyspace string
+}
+
+// DefaultKeyspaceName is the keyspace accessed in API V2 if no keyspace is set
+const DefaultKeyspaceName = "DEFAULT"
+
+var (
+	_apiMutex sync.RWMutex
+	// the API option of the clients created without the explicit one, like
+	// _defaultSecurity
+	_defaultAPI APIOption
+)
+
+// SetDefaultAPI sets the API option used by .connect, copy and diff
+func SetDefaultAPI(o APIOption) {
+	_apiMutex.Lock()
+	defer _apiMutex.Unlock()
+	_defaultAPI = o
+}
+
+func GetDefaultAPI() APIOption {
+	_apiMutex.RLock()
+	defer _apiMutex.RUnlock()
+	return _defaultAPI
+}
+
+func (o APIOption) Validate() error {
+	switch o.Version {
+	case 0, 1:
+		if o.Keyspace != "" {
+			return errors.New("keyspace requires --api-version=2")
+		}
+		return nil
+	case 2:
+		return nil
+	default:
+		return errors.Errorf("invalid API version: %d, accepted values: [1 | 2]", o.Version)
+	}
+}
+
+func (o APIOption) kvrpcVersion() kvrpcpb.APIVersion {
+	if o.Version == 2 {
+		return kvrpcpb.APIVersion_V2
+	}
+	return kvrpcpb.APIVersion_V1
+}
+
+// KeyspaceName returns the name of the keyspace accessed, which is empty in
+// API V1
+func (o APIOption) KeyspaceName() string {
+	switch {
+	case o.Version != 2:
+		return ""
+	case o.Keyspace == "":
+		return DefaultKeyspaceName
+	default:
+		return o.Keyspace
+	}
+}
+
+// ClientAPI returns the API option the client is connected with
+func ClientAPI(c Client) APIOption {
+	switch c := c.(type) {
+	case *rawkvClient:
+		return c.api
+	case *txnkvClient:
+		return c.api
+	}
+	return APIOption{}
+}
+
+// KeyspaceInfo is a keyspace returned by the PD HTTP API
+type KeyspaceInfo struct {
+	ID             uint32            `json:"id"`
+	Name           string            `json:"name"`
+	State          string            `json:"state"`
+	CreatedAt      int64             `json:"created_at"`
+	StateChangedAt int64             `json:"state_changed_at"`
+	Config         map[string]string `json:"config"`
+}
+
+func (KeyspaceInfo) TableTitle() []string {
+	return []string{"ID", "Name", "State", "Created At", "State Changed At"}
+}
+
+func (k KeyspaceInfo) Flatten() []string {
+	formatTime := func(ts int64) string {
+		if ts == 0 {
+			return ""
+		}
+		return time.Unix(ts, 0).Format(time.RFC3339)
+	}
+	return []string{fmt.Sprintf("%d", k.ID), k.Name, k.State, formatTime(k.CreatedAt), formatTime(k.StateChangedAt)}
+}
+
+func (k KeyspaceInfo) String() string {
+	return fmt.Sprintf("id:\"%d\" name:\"%s\" state:\"%s\"", k.ID, k.Name, k.State)
+}
+
+// how many keyspaces to load from PD at a time
+const loadKeyspacesBatchSize = 100
+
+// GetKeyspaces lists the keyspaces through the HTTP API of the PD leader,
+// limit <= 0 means no limit
+func GetKeyspaces(ctx context.Context, c Client, limit int) ([]KeyspaceInfo, error) {
+	var (
+		ret       []KeyspaceInfo
+		pageToken string
+	)
+	for limit <= 0 || len(ret) < limit {
+		params := url.Values{}
+		params.Set("limit", fmt.Sprintf("%d", loadKeyspacesBatchSize))
+		if pageToken != "" {
+			params.Set("page_token", pageToken)
+		}
+		var resp struct {
+			Keyspaces     []KeyspaceInfo `json:"keyspaces"`
+			NextPageToken string         `json:"next_page_token"`
+		}
+		if err := pdHTTPGet(ctx, c, "/pd/api/v2/keyspaces?"+params.Encode(), &resp); err != nil {
+			return nil, err
+		}
+		ret = append(ret, resp.Keyspaces...)
+		if resp.NextPageToken == "" || len(resp.Keyspaces) == 0 {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret, nil
+}
+
+// clientSecurity returns the TLS config the client is connected with
+func clientSecurity(c Client) SecurityOption {
+	switch c := c.(type) {
+	case *rawkvClient:
+		return c.security
+	case *txnkvClient:
+		return c.security
+	}
+	return SecurityOption{}
+}
+
+// pdHTTPGet requests the HTTP API of the PD leader, and decodes the JSON
+// response into v
+func pdHTTPGet(ctx context.Context, c Client, path string, v interface{}) error {
+	if c.GetClientMode() == NONE_CLIENT {
+		return ErrNotConnected
+	}
+	pdClient := c.GetPDClient()
+	if pdClient == nil {
+		return errors.New("PD is not available in mem mode")
+	}
+	leader := pdClient.GetLeaderURL()
+	if leader == "" {
+		return errors.New("PD leader is unknown")
+	}
+	if !strings.Contains(leader, "://") {
+		leader = "http://" + leader
+	}
+	security := clientSecurity(c).tikvSecurity()
+	tlsConfig, err := security.ToTLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		leader = strings.Replace(leader, "http://", "https://", 1)
+	}
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   30 * time.Second,
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(leader, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.Errorf("%s is not found, the PD may not support it: %s", path, strings.TrimSpace(string(body)))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("PD returns %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return errors.Wrapf(json.Unmarshal(body, v), "failed to decode the response of %s", path)
+}
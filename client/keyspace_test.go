@@ -0,0 +1,51 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAPIOption(t *testing.T) {
+	cases := []struct {
+		opt      APIOption
+		keyspace string
+		wantErr  string
+	}{
+		{APIOption{}, "", ""},
+		{APIOption{Version: 1}, "", ""},
+		{APIOption{Version: 2}, DefaultKeyspaceName, ""},
+		{APIOption{Version: 2, Keyspace: "ks1"}, "ks1", ""},
+		{APIOption{Keyspace: "ks1"}, "", "keyspace requires --api-version=2"},
+		{APIOption{Version: 1, Keyspace: "ks1"}, "", "keyspace requires --api-version=2"},
+		{APIOption{Version: 3}, "", "invalid API version: 3"},
+	}
+	for _, tc := range cases {
+		err := tc.opt.Validate()
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%+v: got error %v, want %q", tc.opt, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: %v", tc.opt, err)
+			continue
+		}
+		if got := tc.opt.KeyspaceName(); got != tc.keyspace {
+			t.Errorf("%+v: got keyspace %q, want %q", tc.opt, got, tc.keyspace)
+		}
+	}
+
+	// the API option is ignored in mem mode
+	c, err := NewClient(nil, "mem", SecurityOption{}, APIOption{Version: 2, Keyspace: "ks1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := ClientAPI(c).KeyspaceName(); got != "" {
+		t.Fatalf("got keyspace %q in mem mode", got)
+	}
+	if _, err := NewClient(nil, "mem", SecurityOption{}, APIOption{Keyspace: "ks1"}); err == nil {
+		t.Fatal("keyspace without API V2 should fail")
+	}
+}
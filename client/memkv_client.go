@@ -0,0 +1,274 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/utils"
+	pd "github.com/tikv/pd/client"
+)
+
+// memkvClient is an ordered in-memory implementation of Client, it doesn't
+// need a running TiKV cluster, useful for demos, scripts and tests.
+type memkvClient struct {
+	mu   sync.RWMutex
+	data KVS // sorted by key
+}
+
+func newMemKVClient() *memkvClient {
+	return &memkvClient{}
+}
+
+func (c *memkvClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = nil
+}
+
+func (c *memkvClient) GetClientMode() TiKV_MODE {
+	return MEM_CLIENT
+}
+
+func (c *memkvClient) GetClusterID() string {
+	return "memory"
+}
+
+func (c *memkvClient) GetStores() ([]StoreInfo, error) {
+	return nil, errors.New("memkvClient does not support GetStores()")
+}
+
+func (c *memkvClient) GetPDs() ([]PDInfo, error) {
+	return nil, errors.New("memkvClient does not support GetPDs()")
+}
+
+func (c *memkvClient) GetPDClient() pd.Client {
+	return nil
+}
+
+// search returns the position of the first key >= k, and whether k exists.
+// caller should hold the lock
+func (c *memkvClient) search(k []byte) (int, bool) {
+	idx := sort.Search(len(c.data), func(i int) bool {
+		return bytes.Compare(c.data[i].K, k) >= 0
+	})
+	return idx, idx < len(c.data) && bytes.Equal(c.data[idx].K, k)
+}
+
+// caller should hold the lock
+func (c *memkvClient) set(kv KV) {
+	kv = KV{
+		K: append([]byte{}, kv.K...),
+		V: append([]byte{}, kv.V...),
+	}
+	idx, found := c.search(kv.K)
+	if found {
+		c.data[idx] = kv
+		return
+	}
+	c.data = append(c.data, KV{})
+	copy(c.data[idx+1:], c.data[idx:])
+	c.data[idx] = kv
+}
+
+// caller should hold the lock
+func (c *memkvClient) remove(k []byte) {
+	idx, found := c.search(k)
+	if !found {
+		return
+	}
+	c.data = append(c.data[:idx], c.data[idx+1:]...)
+}
+
+func (c *memkvClient) Put(ctx context.Context, kv KV) error {
+	if err := checkNoTTL(ctx); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(kv)
+	return nil
+}
+
+func (c *memkvClient) BatchPut(ctx context.Context, kvs []KV) error {
+	if err := checkNoTTL(ctx); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, kv := range kvs {
+		c.set(kv)
+	}
+	return nil
+}
+
+func (c *memkvClient) BatchGet(ctx context.Context, keys []Key) (KVS, error) {
+	if err := checkNoReadTS(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var ret KVS
+	for _, k := range keys {
+		if idx, found := c.search(k); found {
+			ret = append(ret, KV{K: k, V: append([]byte{}, c.data[idx].V...)})
+		}
+	}
+	return ret, nil
+}
+
+func (c *memkvClient) Get(ctx context.Context, k Key) (KV, error) {
+	if err := checkNoReadTS(ctx); err != nil {
+		return KV{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	idx, found := c.search(k)
+	if !found {
+		// same error message as txn mode, query relies on it
+		return KV{}, errors.New("not exist")
+	}
+	return KV{K: k, V: append([]byte{}, c.data[idx].V...)}, nil
+}
+
+func (c *memkvClient) Scan(ctx context.Context, startKey []byte) (KVS, int, error) {
+	scanOpts := utils.PropFromContext(ctx)
+	if err := checkNoReadTS(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	strictPrefix := scanOpts.GetBool(tcli.ScanOptStrictPrefix, false)
+	countOnly := scanOpts.GetBool(tcli.ScanOptCountOnly, false)
+	keyOnly := scanOpts.GetBool(tcli.ScanOptKeyOnly, false)
+	// count only mode will ignore this
+	limit := scanOpts.GetInt(tcli.ScanOptLimit, 100)
+	// nil means no upper bound
+	endKey, err := utils.GetStringLitOpt(scanOpts, tcli.ScanOptEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// positions of keys in [startKey, endKey)
+	lower, _ := c.search(startKey)
+	upper := len(c.data)
+	if len(endKey) > 0 {
+		upper, _ = c.search(endKey)
+	}
+	if strictPrefix {
+		end := lower
+		for end < upper && bytes.HasPrefix(c.data[end].K, startKey) {
+			end++
+		}
+		upper = end
+	}
+	idx, step := lower, 1
+	// in reverse mode, scan keys in [startKey, endKey) from the end
+	if scanOpts.GetBool(tcli.ScanOptReverse, false) {
+		idx, step = upper-1, -1
+	}
+
+	var ret []KV
+	var lastKey KV
+	count := 0
+	for ; idx >= lower && idx < upper; idx += step {
+		if !countOnly && limit == 0 {
+			break
+		}
+		kv := c.data[idx]
+		// count only will not use limit
+		if !countOnly {
+			if keyOnly {
+				ret = append(ret, KV{K: append([]byte{}, kv.K...)})
+			} else {
+				ret = append(ret, KV{K: append([]byte{}, kv.K...), V: append([]byte{}, kv.V...)})
+			}
+			limit--
+		}
+		count++
+		lastKey.K = kv.K
+	}
+	if countOnly {
+		ret = append(ret, KV{K: []byte("Count"), V: []byte(fmt.Sprintf("%d", count))})
+		ret = append(ret, KV{K: []byte("Last Key"), V: append([]byte{}, lastKey.K...)})
+	}
+	return ret, count, nil
+}
+
+func (c *memkvClient) Delete(ctx context.Context, k Key) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(k)
+	return nil
+}
+
+func (c *memkvClient) BatchDelete(ctx context.Context, kvs []KV) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, kv := range kvs {
+		c.remove(kv.K)
+	}
+	return nil
+}
+
+func (c *memkvClient) GetTTL(ctx context.Context, k Key) (time.Duration, error) {
+	return 0, errors.New("ttl is only supported in raw mode")
+}
+
+func (c *memkvClient) CompareAndSwap(ctx context.Context, k Key, oldValue, newValue []byte) ([]byte, bool, error) {
+	if err := checkNoTTL(ctx); err != nil {
+		return nil, false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var prev []byte
+	if idx, found := c.search(k); found {
+		prev = append([]byte{}, c.data[idx].V...)
+	}
+	if !casMatch(prev, oldValue) {
+		return prev, false, nil
+	}
+	c.set(KV{K: k, V: newValue})
+	return prev, true, nil
+}
+
+// return lastKey, delete count, error
+func (c *memkvClient) DeletePrefix(ctx context.Context, prefix Key, limit int) (Key, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start, _ := c.search(prefix)
+	end := start
+	for end < len(c.data) && end-start < limit && bytes.HasPrefix(c.data[end].K, prefix) {
+		end++
+	}
+	if end == start {
+		return nil, 0, nil
+	}
+	lastKey := append([]byte{}, c.data[end-1].K...)
+	c.data = append(c.data[:start], c.data[end:]...)
+	return lastKey, end - start, nil
+}
+
+func (c *memkvClient) Begin(ctx context.Context, pessimistic bool) error {
+	return errTxnNotSupported
+}
+
+func (c *memkvClient) Commit(ctx context.Context) error {
+	return errTxnNotSupported
+}
+
+func (c *memkvClient) Rollback(ctx context.Context) error {
+	return errTxnNotSupported
+}
+
+func (c *memkvClient) GetTxnInfo() *TxnInfo {
+	return nil
+}
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+)
+
+// newTestMemKV returns a memkv client with the keys, the value of a key is
+// the key itself
+func newTestMemKV(t *testing.T, keys ...string) *memkvClient {
+	t.Helper()
+	c := newMemKVClient()
+	var kvs []KV
+	for _, k := range keys {
+		kvs = append(kvs, KV{K: []byte(k), V: []byte(k)})
+	}
+	if err := c.BatchPut(context.TODO(), kvs); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestMemKVReadWrite(t *testing.T) {
+	ctx := context.TODO()
+	c := newTestMemKV(t, "a", "b", "c")
+
+	if err := c.Put(ctx, KV{K: []byte("b"), V: []byte("new")}); err != nil {
+		t.Fatal(err)
+	}
+	if kv, err := c.Get(ctx, Key("b")); err != nil || string(kv.V) != "new" {
+		t.Fatalf("got %v, %v, want the overwritten value", kv, err)
+	}
+	if err := c.Delete(ctx, Key("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.BatchDelete(ctx, []KV{{K: []byte("c")}, {K: []byte("not exists")}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "c", "not exists"} {
+		// query relies on the same error as txn mode
+		if _, err := c.Get(ctx, Key(k)); err == nil || err.Error() != "not exist" {
+			t.Fatalf("got error %v of %s, want not exist", err, k)
+		}
+	}
+	if len(c.data) != 1 || string(c.data[0].K) != "b" {
+		t.Fatalf("got %v, want only b", c.data)
+	}
+}
+
+func TestMemKVScan(t *testing.T) {
+	c := newTestMemKV(t, "a", "a1", "a2", "ab", "b", "b1", "c")
+
+	cases := []struct {
+		name  string
+		start string
+		opts  map[string]string
+		want  []string
+	}{
+		{"all", "", nil, []string{"a", "a1", "a2", "ab", "b", "b1", "c"}},
+		{"start", "a2", nil, []string{"a2", "ab", "b", "b1", "c"}},
+		{"start not exists", "a3", nil, []string{"ab", "b", "b1", "c"}},
+		{"limit", "a", map[string]string{tcli.ScanOptLimit: "2"}, []string{"a", "a1"}},
+		{"limit 0", "a", map[string]string{tcli.ScanOptLimit: "0"}, nil},
+		{"end", "a1", map[string]string{tcli.ScanOptEnd: "b"}, []string{"a1", "a2", "ab"}},
+		{"end before start", "b", map[string]string{tcli.ScanOptEnd: "a"}, nil},
+		{"end in hex", "a", map[string]string{tcli.ScanOptEnd: "h'6131'"}, []string{"a"}},
+		{"reverse", "a", map[string]string{tcli.ScanOptReverse: "true"}, []string{"c", "b1", "b", "ab", "a2", "a1", "a"}},
+		{"reverse with end", "a1", map[string]string{tcli.ScanOptReverse: "true", tcli.ScanOptEnd: "b"}, []string{"ab", "a2", "a1"}},
+		{"reverse with limit", "a", map[string]string{tcli.ScanOptReverse: "true", tcli.ScanOptLimit: "2"}, []string{"c", "b1"}},
+		{"strict prefix", "a", map[string]string{tcli.ScanOptStrictPrefix: "true"}, []string{"a", "a1", "a2", "ab"}},
+		{"strict prefix not exists", "d", map[string]string{tcli.ScanOptStrictPrefix: "true"}, nil},
+		{"strict prefix with end", "a", map[string]string{tcli.ScanOptStrictPrefix: "true", tcli.ScanOptEnd: "a2"}, []string{"a", "a1"}},
+		{"strict prefix reverse", "b", map[string]string{tcli.ScanOptStrictPrefix: "true", tcli.ScanOptReverse: "true"}, []string{"b1", "b"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := properties.NewProperties()
+			for k, v := range tc.opts {
+				opt.Set(k, v)
+			}
+			kvs, cnt, err := c.Scan(utils.ContextWithProp(context.TODO(), opt), []byte(tc.start))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, kv := range kvs {
+				got = append(got, string(kv.K))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+			if cnt != len(tc.want) {
+				t.Fatalf("got count %d, want %d", cnt, len(tc.want))
+			}
+		})
+	}
+}
+
+func TestMemKVScanCountOnly(t *testing.T) {
+	c := newTestMemKV(t, "a", "a1", "a2", "b")
+
+	cases := []struct {
+		name      string
+		start     string
+		opts      map[string]string
+		wantCount int
+		wantLast  string
+	}{
+		{"all", "", nil, 4, "b"},
+		// the limit is ignored in count only mode
+		{"limit", "", map[string]string{tcli.ScanOptLimit: "1"}, 4, "b"},
+		{"strict prefix", "a", map[string]string{tcli.ScanOptStrictPrefix: "true"}, 3, "a2"},
+		{"end", "a", map[string]string{tcli.ScanOptEnd: "a2"}, 2, "a1"},
+		{"empty", "c", nil, 0, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := properties.NewProperties()
+			opt.Set(tcli.ScanOptCountOnly, "true")
+			for k, v := range tc.opts {
+				opt.Set(k, v)
+			}
+			kvs, cnt, err := c.Scan(utils.ContextWithProp(context.TODO(), opt), []byte(tc.start))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cnt != tc.wantCount {
+				t.Fatalf("got count %d, want %d", cnt, tc.wantCount)
+			}
+			if len(kvs) != 2 || string(kvs[1].V) != tc.wantLast {
+				t.Fatalf("got %v, want last key %q", kvs, tc.wantLast)
+			}
+		})
+	}
+}
+
+func TestMemKVScanKeyOnly(t *testing.T) {
+	c := newTestMemKV(t, "a", "b")
+	opt := properties.NewProperties()
+	opt.Set(tcli.ScanOptKeyOnly, "true")
+	kvs, _, err := c.Scan(utils.ContextWithProp(context.TODO(), opt), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range kvs {
+		if len(kv.V) != 0 {
+			t.Fatalf("got value %q of key %q in key only mode", kv.V, kv.K)
+		}
+	}
+}
+
+func TestMemKVScanAsOf(t *testing.T) {
+	c := newTestMemKV(t, "a")
+	opt := properties.NewProperties()
+	opt.Set(tcli.ScanOptAsOf, "1")
+	if _, _, err := c.Scan(utils.ContextWithProp(context.TODO(), opt), nil); err == nil {
+		t.Fatal("snapshot read should fail in mem mode")
+	}
+	if _, err := c.Get(utils.ContextWithProp(context.TODO(), opt), Key("a")); err == nil {
+		t.Fatal("snapshot read should fail in mem mode")
+	}
+	opt.Set(tcli.ScanOptAsOf, AsOfLatest)
+	if _, _, err := c.Scan(utils.ContextWithProp(context.TODO(), opt), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemKVDeletePrefix(t *testing.T) {
+	cases := []struct {
+		name     string
+		prefix   string
+		limit    int
+		wantLast string
+		wantCnt  int
+		wantRest []string
+	}{
+		{"all of prefix", "a", 100, "ab", 3, []string{"b"}},
+		{"limit", "a", 2, "a1", 2, []string{"ab", "b"}},
+		{"not exists", "c", 100, "", 0, []string{"a", "a1", "ab", "b"}},
+		{"empty prefix", "", 100, "b", 4, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestMemKV(t, "a", "a1", "ab", "b")
+			lastKey, cnt, err := c.DeletePrefix(context.TODO(), []byte(tc.prefix), tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(lastKey) != tc.wantLast || cnt != tc.wantCnt {
+				t.Fatalf("got last key %q, count %d, want %q, %d", lastKey, cnt, tc.wantLast, tc.wantCnt)
+			}
+			var rest []string
+			for _, kv := range c.data {
+				rest = append(rest, string(kv.K))
+			}
+			if !reflect.DeepEqual(rest, tc.wantRest) {
+				t.Fatalf("got rest keys %q, want %q", rest, tc.wantRest)
+			}
+		})
+	}
+}
@@ -5,34 +5,48 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
-	"github.com/c4pt0r/log"
 	"github.com/c4pt0r/tcli"
 	"github.com/c4pt0r/tcli/utils"
-	"github.com/tikv/client-go/v2/config"
 	"github.com/tikv/client-go/v2/rawkv"
 	pd "github.com/tikv/pd/client"
 )
 
 var MaxRawKVScanLimit = 10240
 
-func newRawKVClient(pdAddr []string) *rawkvClient {
-	client, err := rawkv.NewClient(context.TODO(), pdAddr, config.DefaultConfig().Security)
+func newRawKVClient(pdAddr []string, security SecurityOption, api APIOption) (*rawkvClient, error) {
+	client, err := rawkv.NewClientWithOpts(context.TODO(), pdAddr,
+		rawkv.WithSecurity(security.tikvSecurity()),
+		rawkv.WithAPIVersion(api.kvrpcVersion()),
+		rawkv.WithKeyspace(api.Keyspace))
 	if err != nil {
-		log.F(err)
+		return nil, err
+	}
+	rpc, err := newRawRPC(client, security)
+	if err != nil {
+		client.Close()
+		return nil, err
 	}
 	return &rawkvClient{
 		rawClient: client,
+		rpc:       rpc,
 		pdAddr:    pdAddr,
-	}
+		security:  security,
+		api:       api,
+	}, nil
 }
 
 type rawkvClient struct {
 	rawClient *rawkv.Client
+	rpc       *rawRPC
 	pdAddr    []string
+	security  SecurityOption
+	api       APIOption
 }
 
 func (c *rawkvClient) Close() {
+	c.rpc.Close()
 	if c.rawClient != nil {
 		c.rawClient.Close()
 	}
@@ -47,32 +61,90 @@ func (c *rawkvClient) GetClusterID() string {
 }
 
 func (c *rawkvClient) GetStores() ([]StoreInfo, error) {
-	panic("rawkvClient does not support GetStores()")
+	return getStores(c.GetPDClient())
 }
 
 func (c *rawkvClient) GetPDs() ([]PDInfo, error) {
-	panic("rawkvClient does not support GetPDs()")
+	return getPDs(c.GetPDClient())
 }
 
+// GetPDClient returns the PD client of the raw client, which encodes and
+// decodes the region keys in the same way as the requests
 func (c *rawkvClient) GetPDClient() pd.Client {
-	panic("rawkvClient does not support GetPDClient()")
+	return c.rawClient.GetPDClient()
 }
 
 func (c *rawkvClient) Put(ctx context.Context, kv KV) error {
+	ttl, err := getTTL(ctx)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return c.rawClient.PutWithTTL(context.TODO(), kv.K, kv.V, ttl)
+	}
 	return c.rawClient.Put(context.TODO(), kv.K, kv.V)
 }
 
 func (c *rawkvClient) BatchPut(ctx context.Context, kvs []KV) error {
+	ttl, err := getTTL(ctx)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		keys := make([][]byte, 0, len(kvs))
+		values := make([][]byte, 0, len(kvs))
+		ttls := make([]uint64, 0, len(kvs))
+		for _, kv := range kvs {
+			keys = append(keys, kv.K)
+			values = append(values, kv.V)
+			ttls = append(ttls, ttl)
+		}
+		return c.rawClient.BatchPutWithTTL(context.TODO(), keys, values, ttls)
+	}
 	for _, kv := range kvs {
-		err := c.rawClient.Put(context.TODO(), kv.K[:], kv.V[:])
-		if err != nil {
+		if err := c.rawClient.Put(context.TODO(), kv.K[:], kv.V[:]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (c *rawkvClient) GetTTL(ctx context.Context, k Key) (time.Duration, error) {
+	ttl, err := c.rawClient.GetKeyTTL(context.TODO(), k)
+	if err != nil {
+		return 0, err
+	}
+	if ttl == nil {
+		return 0, errors.New("not exist")
+	}
+	return time.Duration(*ttl) * time.Second, nil
+}
+
+func (c *rawkvClient) BatchGet(ctx context.Context, keys []Key) (KVS, error) {
+	if err := checkNoReadTS(ctx); err != nil {
+		return nil, err
+	}
+	rawKeys := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		rawKeys = append(rawKeys, k)
+	}
+	values, err := c.rawClient.BatchGet(context.TODO(), rawKeys)
+	if err != nil {
+		return nil, err
+	}
+	var ret KVS
+	for i, v := range values {
+		if v != nil {
+			ret = append(ret, KV{K: keys[i], V: v})
+		}
+	}
+	return ret, nil
+}
+
 func (c *rawkvClient) Get(ctx context.Context, k Key) (KV, error) {
+	if err := checkNoReadTS(ctx); err != nil {
+		return KV{}, err
+	}
 	v, err := c.rawClient.Get(context.TODO(), k)
 	if err != nil {
 		return KV{}, err
@@ -85,6 +157,9 @@ func (c *rawkvClient) Get(ctx context.Context, k Key) (KV, error) {
 
 func (c *rawkvClient) Scan(ctx context.Context, prefix []byte) (KVS, int, error) {
 	scanOpts := utils.PropFromContext(ctx)
+	if err := checkNoReadTS(ctx); err != nil {
+		return nil, 0, err
+	}
 
 	strictPrefix := scanOpts.GetBool(tcli.ScanOptStrictPrefix, false)
 	countOnly := scanOpts.GetBool(tcli.ScanOptCountOnly, false)
@@ -96,7 +171,19 @@ func (c *rawkvClient) Scan(ctx context.Context, prefix []byte) (KVS, int, error)
 		limit = MaxRawKVScanLimit
 	}
 
-	keys, values, err := c.rawClient.Scan(ctx, prefix, []byte{}, limit)
+	// empty means no upper bound
+	endKey, err := utils.GetStringLitOpt(scanOpts, tcli.ScanOptEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var keys, values [][]byte
+	if scanOpts.GetBool(tcli.ScanOptReverse, false) {
+		// in reverse mode, scan keys in [prefix, endKey) from the end
+		keys, values, err = c.rawClient.ReverseScan(ctx, reverseScanUpperBound(prefix, endKey, strictPrefix), prefix, limit)
+	} else {
+		keys, values, err = c.rawClient.Scan(ctx, prefix, endKey, limit)
+	}
 	if err != nil {
 		return nil, 0, err
 	}
@@ -139,12 +226,40 @@ func (c *rawkvClient) BatchDelete(ctx context.Context, kvs []KV) error {
 }
 
 func (c *rawkvClient) DeletePrefix(ctx context.Context, prefix Key, limit int) (Key, int, error) {
-	endKey := []byte(prefix)
-	endKey[len(endKey)] += 0x1
-	keys, _, err := c.rawClient.Scan(ctx, prefix, endKey, MaxRawKVScanLimit)
+	if limit > MaxRawKVScanLimit {
+		limit = MaxRawKVScanLimit
+	}
+	keys, _, err := c.rawClient.Scan(ctx, prefix, utils.PrefixEnd(prefix), limit)
 	if err != nil {
 		return nil, 0, err
 	}
+	if len(keys) == 0 {
+		return nil, 0, nil
+	}
 	lastKey := Key(keys[len(keys)-1])
 	return lastKey, len(keys), c.rawClient.BatchDelete(context.TODO(), keys)
 }
+
+func (c *rawkvClient) CompareAndSwap(ctx context.Context, k Key, oldValue, newValue []byte) ([]byte, bool, error) {
+	ttl, err := getTTL(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return c.rpc.CompareAndSwap(context.TODO(), k, oldValue, newValue, ttl)
+}
+
+func (c *rawkvClient) Begin(ctx context.Context, pessimistic bool) error {
+	return errTxnNotSupported
+}
+
+func (c *rawkvClient) Commit(ctx context.Context) error {
+	return errTxnNotSupported
+}
+
+func (c *rawkvClient) Rollback(ctx context.Context) error {
+	return errTxnNotSupported
+}
+
+func (c *rawkvClient) GetTxnInfo() *TxnInfo {
+	return nil
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/tikvrpc"
+)
+
+// rawkv.Client can't set the TTL of CompareAndSwap, rawRPC sends it with the
+// region cache and the codec of the raw client, so the keys are encoded in the
+// same way, e.g. with the keyspace prefix in API V2.
+type rawRPC struct {
+	regionCache *tikv.RegionCache
+	rpcClient   tikv.Client
+}
+
+// rawRPCMaxBackoff is the max sleep time (in ms) of retrying a request
+const rawRPCMaxBackoff = 20000
+
+func newRawRPC(rawClient *rawkv.Client, security SecurityOption) (*rawRPC, error) {
+	pdClient, ok := rawClient.GetPDClient().(*tikv.CodecPDClient)
+	if !ok {
+		return nil, errors.New("the PD client of the raw client has no codec")
+	}
+	return &rawRPC{
+		regionCache: tikv.NewRegionCache(pdClient),
+		rpcClient: tikv.NewRPCClient(
+			tikv.WithSecurity(security.tikvSecurity()),
+			tikv.WithCodec(pdClient.GetCodec()),
+		),
+	}, nil
+}
+
+func (r *rawRPC) Close() {
+	r.regionCache.Close()
+	r.rpcClient.Close()
+}
+
+// send sends req to the leader of the region containing key, and retries on
+// region errors with the reloaded region info
+func (r *rawRPC) send(ctx context.Context, key []byte, req *tikvrpc.Request) (*tikvrpc.Response, error) {
+	bo := tikv.NewBackofferWithVars(ctx, rawRPCMaxBackoff, nil)
+	sender := tikv.NewRegionRequestSender(r.regionCache, r.rpcClient)
+	for {
+		loc, err := r.regionCache.LocateKey(bo, key)
+		if err != nil {
+			return nil, err
+		}
+		resp, _, err := sender.SendReq(bo, req, loc.Region, tikv.ReadTimeoutShort)
+		if err != nil {
+			return nil, err
+		}
+		regionErr, err := resp.GetRegionError()
+		if err != nil {
+			return nil, err
+		}
+		if regionErr != nil {
+			if err := bo.Backoff(tikv.BoRegionMiss(), errors.New(regionErr.String())); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.Resp == nil {
+			return nil, tikverr.ErrBodyMissing
+		}
+		return resp, nil
+	}
+}
+
+// CompareAndSwap sets key to newValue with TTL in seconds if its value equals
+// oldValue, returns the previous value (nil if not exists) and whether
+// the swap happened
+func (r *rawRPC) CompareAndSwap(ctx context.Context, key, oldValue, newValue []byte, ttl uint64) ([]byte, bool, error) {
+	req := tikvrpc.NewRequest(tikvrpc.CmdRawCompareAndSwap, &kvrpcpb.RawCASRequest{
+		Key:              key,
+		Value:            newValue,
+		PreviousNotExist: oldValue == nil,
+		PreviousValue:    oldValue,
+		Ttl:              ttl,
+	})
+	resp, err := r.send(ctx, key, req)
+	if err != nil {
+		return nil, false, err
+	}
+	casResp := resp.Resp.(*kvrpcpb.RawCASResponse)
+	if casResp.GetError() != "" {
+		return nil, false, fmt.Errorf("compare and swap: %s", casResp.GetError())
+	}
+	var prev []byte
+	if !casResp.GetPreviousNotExist() {
+		prev = casResp.GetPreviousValue()
+		if prev == nil {
+			prev = []byte{}
+		}
+	}
+	return prev, casResp.GetSucceed(), nil
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// KeyRange is the key range [Start, End), empty End means no upper bound
+type KeyRange struct {
+	Start Key
+	End   Key
+}
+
+// ErrStopScan can be returned by the callback of ParallelScan to stop the
+// scan without an error
+var ErrStopScan = errors.New("stop scan")
+
+// how many regions to load from PD at a time
+const ScanRegionsBatchSize = 128
+
+// ParallelScanOpt is the options of ParallelScan
+type ParallelScanOpt struct {
+	// number of workers, default: 1
+	Concurrency int
+	// number of kv pairs in a batch, default: 1000
+	BatchSize int
+	// if Ordered is set, the callback is called serially with the batches
+	// in key order, otherwise it's called concurrently by the workers
+	Ordered bool
+	// extra scan options, like key-only and as-of
+	ScanOpt *properties.Properties
+}
+
+// SplitRangeByRegions splits [start, end) at the region boundaries, it
+// returns the whole range if c is not backed by a TiKV cluster.
+func SplitRangeByRegions(ctx context.Context, c Client, start, end Key) ([]KeyRange, error) {
+	// mem mode has no PD
+	pdClient := c.GetPDClient()
+	if pdClient == nil {
+		return []KeyRange{{Start: start, End: end}}, nil
+	}
+
+	var ranges []KeyRange
+	cur := start
+	for {
+		regions, err := pdClient.ScanRegions(ctx, cur, end, ScanRegionsBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(regions) == 0 {
+			break
+		}
+		for _, region := range regions {
+			regionEnd := region.Meta.GetEndKey()
+			// the region is before cur
+			if len(regionEnd) > 0 && bytes.Compare(regionEnd, cur) <= 0 {
+				continue
+			}
+			if len(regionEnd) == 0 || (len(end) > 0 && bytes.Compare(regionEnd, end) >= 0) {
+				// the last region
+				return append(ranges, KeyRange{Start: cur, End: end}), nil
+			}
+			ranges = append(ranges, KeyRange{Start: cur, End: regionEnd})
+			cur = regionEnd
+		}
+	}
+	// the rest keys not covered by the regions got from PD
+	return append(ranges, KeyRange{Start: cur, End: end}), nil
+}
+
+// PinSnapshot sets the as-of option of scanOpt to the current timestamp in txn
+// mode, so all the batches of a parallel scan, or several scans sharing the
+// option, read the same snapshot. It's a no-op if there is an open transaction
+// or a read timestamp.
+func PinSnapshot(c Client, scanOpt *properties.Properties) (*properties.Properties, error) {
+	txnClient, ok := c.(*txnkvClient)
+	if !ok || c.GetTxnInfo() != nil {
+		return scanOpt, nil
+	}
+	ret := properties.NewProperties()
+	if scanOpt != nil {
+		ret.Merge(scanOpt)
+	}
+	ts, err := getReadTS(utils.ContextWithProp(context.TODO(), ret))
+	if err != nil || ts != 0 {
+		return ret, err
+	}
+	ts, err = txnClient.txnClient.CurrentTimestamp(oracle.GlobalTxnScope)
+	if err != nil {
+		return nil, err
+	}
+	ret.Set(tcli.ScanOptAsOf, strconv.FormatUint(ts, 10))
+	return ret, nil
+}
+
+// scanRange scans r in batches and calls f with each batch
+func scanRange(ctx context.Context, c Client, r KeyRange, opt ParallelScanOpt, f func(KVS) error) error {
+	scanOpt := properties.NewProperties()
+	if opt.ScanOpt != nil {
+		scanOpt.Merge(opt.ScanOpt)
+	}
+	scanOpt.Set(tcli.ScanOptLimit, strconv.Itoa(opt.BatchSize))
+	scanOpt.Set(tcli.ScanOptStrictPrefix, "false")
+	scanOpt.Set(tcli.ScanOptCountOnly, "false")
+	scanOpt.Set(tcli.ScanOptReverse, "false")
+	if len(r.End) > 0 {
+		scanOpt.Set(tcli.ScanOptEnd, utils.Bytes2StrLit(r.End))
+	} else {
+		scanOpt.Delete(tcli.ScanOptEnd)
+	}
+	scanCtx := utils.ContextWithProp(ctx, scanOpt)
+
+	start := r.Start
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		kvs, cnt, err := c.Scan(scanCtx, start)
+		if err != nil {
+			return err
+		}
+		if cnt == 0 {
+			return nil
+		}
+		if err := f(kvs); err != nil {
+			return err
+		}
+		start = utils.NextKey(kvs[len(kvs)-1].K)
+	}
+}
+
+// ParallelScan scans the keys in [start, end) region by region with
+// opt.Concurrency workers, and calls f with the scanned batches.
+// The scan stops at the first error, when f returns ErrStopScan or when
+// ctx is canceled.
+func ParallelScan(ctx context.Context, c Client, start, end Key, opt ParallelScanOpt, f func(KVS) error) error {
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = 1
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 1000
+	}
+	if c.GetClientMode() == RAW_CLIENT && opt.BatchSize > MaxRawKVScanLimit {
+		opt.BatchSize = MaxRawKVScanLimit
+	}
+	// the open transaction can't be used concurrently
+	if c.GetTxnInfo() != nil {
+		opt.Concurrency = 1
+	}
+
+	scanOpt, err := PinSnapshot(c, opt.ScanOpt)
+	if err != nil {
+		return err
+	}
+	opt.ScanOpt = scanOpt
+
+	ranges, err := SplitRangeByRegions(ctx, c, start, end)
+	if err != nil {
+		return err
+	}
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// in ordered mode, each range has a channel of its batches, which are
+	// consumed range by range
+	var chans []chan KVS
+	if opt.Ordered {
+		chans = make([]chan KVS, len(ranges))
+		for i := range chans {
+			chans[i] = make(chan KVS, 4)
+		}
+	}
+
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < opt.Concurrency && w < len(ranges); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(ranges) {
+					return
+				}
+				emit := f
+				if opt.Ordered {
+					ch := chans[i]
+					emit = func(kvs KVS) error {
+						select {
+						case ch <- kvs:
+							return nil
+						case <-scanCtx.Done():
+							return scanCtx.Err()
+						}
+					}
+				}
+				err := scanRange(scanCtx, c, ranges[i], opt, emit)
+				// set the error before closing the channel, so the consumer
+				// sees the scan is canceled instead of waiting for the next
+				// range, which is never scanned
+				if err != nil {
+					setErr(err)
+				}
+				if opt.Ordered {
+					close(chans[i])
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	if opt.Ordered {
+	consume:
+		for _, ch := range chans {
+			for {
+				var (
+					kvs KVS
+					ok  bool
+				)
+				select {
+				case kvs, ok = <-ch:
+				case <-scanCtx.Done():
+					break consume
+				}
+				if !ok {
+					break
+				}
+				if err := f(kvs); err != nil {
+					setErr(err)
+					break consume
+				}
+			}
+		}
+	}
+	wg.Wait()
+
+	if firstErr == ErrStopScan {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("scan canceled: %w", err)
+	}
+	return firstErr
+}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/magiconair/properties"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	pd "github.com/tikv/pd/client"
+)
+
+// splitPD is a PD client which only serves ScanRegions, the regions are
+// split at the split keys
+type splitPD struct {
+	pd.Client
+	splits [][]byte
+}
+
+func (p *splitPD) ScanRegions(ctx context.Context, key, endKey []byte, limit int, opts ...pd.GetRegionOption) ([]*pd.Region, error) {
+	var ret []*pd.Region
+	for i := 0; i <= len(p.splits) && len(ret) < limit; i++ {
+		var start, end []byte
+		if i > 0 {
+			start = p.splits[i-1]
+		}
+		if i < len(p.splits) {
+			end = p.splits[i]
+		}
+		// skip the regions not overlapping [key, endKey)
+		if len(end) > 0 && bytes.Compare(end, key) <= 0 {
+			continue
+		}
+		if len(endKey) > 0 && bytes.Compare(start, endKey) >= 0 {
+			break
+		}
+		ret = append(ret, &pd.Region{Meta: &metapb.Region{StartKey: start, EndKey: end}})
+	}
+	return ret, nil
+}
+
+// splitMemKV is a memkv client with regions
+type splitMemKV struct {
+	*memkvClient
+	pd *splitPD
+}
+
+func (c *splitMemKV) GetPDClient() pd.Client {
+	return c.pd
+}
+
+// newSplitMemKV returns a client with keys k000 ... k<n-1>, split into
+// regions at every step keys
+func newSplitMemKV(t *testing.T, n, step int) *splitMemKV {
+	t.Helper()
+	var keys []string
+	p := &splitPD{}
+	for i := 0; i < n; i++ {
+		keys = append(keys, fmt.Sprintf("k%03d", i))
+		if i > 0 && i%step == 0 {
+			p.splits = append(p.splits, []byte(keys[i]))
+		}
+	}
+	return &splitMemKV{memkvClient: newTestMemKV(t, keys...), pd: p}
+}
+
+func TestSplitRangeByRegions(t *testing.T) {
+	c := newSplitMemKV(t, 100, 25) // split at k025, k050, k075
+
+	cases := []struct {
+		name       string
+		start, end string
+		want       []string
+	}{
+		{"all", "", "", []string{"-k025", "k025-k050", "k050-k075", "k075-"}},
+		{"in a region", "k030", "k040", []string{"k030-k040"}},
+		{"across regions", "k030", "k060", []string{"k030-k050", "k050-k060"}},
+		{"end at a split key", "k010", "k050", []string{"k010-k025", "k025-k050"}},
+		{"no end", "k060", "", []string{"k060-k075", "k075-"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ranges, err := SplitRangeByRegions(context.TODO(), c, []byte(tc.start), []byte(tc.end))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range ranges {
+				got = append(got, string(r.Start)+"-"+string(r.End))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	// mem mode has no regions
+	ranges, err := SplitRangeByRegions(context.TODO(), newMemKVClient(), []byte("a"), []byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || string(ranges[0].Start) != "a" || string(ranges[0].End) != "b" {
+		t.Fatalf("got %v, want the whole range", ranges)
+	}
+}
+
+func TestParallelScan(t *testing.T) {
+	c := newSplitMemKV(t, 100, 10)
+	keys := func(from, to int) []string {
+		var ret []string
+		for i := from; i < to; i++ {
+			ret = append(ret, fmt.Sprintf("k%03d", i))
+		}
+		return ret
+	}
+
+	cases := []struct {
+		name       string
+		start, end string
+		opt        ParallelScanOpt
+		want       []string
+	}{
+		{"serial", "", "", ParallelScanOpt{BatchSize: 7}, keys(0, 100)},
+		{"ordered", "", "", ParallelScanOpt{Concurrency: 8, BatchSize: 3, Ordered: true}, keys(0, 100)},
+		{"unordered", "", "", ParallelScanOpt{Concurrency: 8, BatchSize: 3}, keys(0, 100)},
+		{"range", "k015", "k042", ParallelScanOpt{Concurrency: 4, BatchSize: 4, Ordered: true}, keys(15, 42)},
+		{"empty range", "k200", "", ParallelScanOpt{Concurrency: 4, Ordered: true}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mu  sync.Mutex
+				got []string
+			)
+			err := ParallelScan(context.TODO(), c, []byte(tc.start), []byte(tc.end), tc.opt, func(kvs KVS) error {
+				if len(kvs) > tc.opt.BatchSize && tc.opt.BatchSize > 0 {
+					return fmt.Errorf("got %d kv pairs in a batch of %d", len(kvs), tc.opt.BatchSize)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, kv := range kvs {
+					got = append(got, string(kv.K))
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.opt.Ordered {
+				sort.Strings(got)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParallelScanStop(t *testing.T) {
+	c := newSplitMemKV(t, 100, 10)
+
+	// stop after 25 keys, the ordered batches are a prefix of the range
+	var got []string
+	opt := ParallelScanOpt{Concurrency: 8, BatchSize: 5, Ordered: true}
+	err := ParallelScan(context.TODO(), c, nil, nil, opt, func(kvs KVS) error {
+		for _, kv := range kvs {
+			got = append(got, string(kv.K))
+		}
+		if len(got) >= 25 {
+			return ErrStopScan
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 25 || got[0] != "k000" || got[24] != "k024" {
+		t.Fatalf("got %q, want k000 ... k024", got)
+	}
+
+	// the other errors are returned
+	errTest := errors.New("test")
+	err = ParallelScan(context.TODO(), c, nil, nil, opt, func(kvs KVS) error {
+		return errTest
+	})
+	if err != errTest {
+		t.Fatalf("got error %v, want %v", err, errTest)
+	}
+
+	// canceled
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	if err := ParallelScan(ctx, c, nil, nil, opt, func(kvs KVS) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want canceled", err)
+	}
+}
+
+// failScanMemKV fails the scans starting from the keys >= failAt
+type failScanMemKV struct {
+	*splitMemKV
+	failAt []byte
+}
+
+var errScanFailed = errors.New("scan failed")
+
+func (c *failScanMemKV) Scan(ctx context.Context, startKey []byte) (KVS, int, error) {
+	if bytes.Compare(startKey, c.failAt) >= 0 {
+		return nil, 0, errScanFailed
+	}
+	return c.splitMemKV.Scan(ctx, startKey)
+}
+
+func TestParallelScanError(t *testing.T) {
+	// fail in the second of the 10 regions
+	c := &failScanMemKV{splitMemKV: newSplitMemKV(t, 100, 10), failAt: []byte("k010")}
+
+	for _, opt := range []ParallelScanOpt{
+		{Concurrency: 1, BatchSize: 5, Ordered: true},
+		{Concurrency: 4, BatchSize: 5, Ordered: true},
+		{Concurrency: 4, BatchSize: 5},
+	} {
+		done := make(chan error, 1)
+		go func() {
+			done <- ParallelScan(context.TODO(), c, nil, nil, opt, func(kvs KVS) error { return nil })
+		}()
+		select {
+		case err := <-done:
+			if err != errScanFailed {
+				t.Fatalf("got error %v with %+v, want %v", err, opt, errScanFailed)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("scan with %+v doesn't return after the error", opt)
+		}
+	}
+}
+
+func TestParallelScanKeyOnly(t *testing.T) {
+	c := newSplitMemKV(t, 30, 10)
+	scanOpt := properties.NewProperties()
+	scanOpt.Set(tcli.ScanOptKeyOnly, "true")
+	// the options set by ParallelScan itself are overridden
+	scanOpt.Set(tcli.ScanOptLimit, "1")
+	scanOpt.Set(tcli.ScanOptReverse, "true")
+	opt := ParallelScanOpt{Concurrency: 2, Ordered: true, ScanOpt: scanOpt}
+	cnt := 0
+	err := ParallelScan(context.TODO(), c, nil, nil, opt, func(kvs KVS) error {
+		for _, kv := range kvs {
+			if len(kv.V) != 0 {
+				return fmt.Errorf("got value of %q in key only mode", kv.K)
+			}
+			if want := fmt.Sprintf("k%03d", cnt); string(kv.K) != want {
+				return fmt.Errorf("got key %q, want %q", kv.K, want)
+			}
+			cnt++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 30 {
+		t.Fatalf("got %d keys, want 30", cnt)
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/tikv/client-go/v2/config"
+)
+
+// SecurityOption is the TLS config of the connections to PD and TiKV, the
+// connections are not encrypted if CAPath is empty
+type SecurityOption struct {
+	CAPath   string
+	CertPath string
+	KeyPath  string
+	// the allowed common names of the certificates, the cluster-verify-cn of
+	// the client-go security config
+	CertAllowedCN []string
+}
+
+var (
+	_securityMutex sync.RWMutex
+	// the security option of the clients created without the explicit one,
+	// e.g. by .connect <pd addrs>
+	_defaultSecurity SecurityOption
+)
+
+// SetDefaultSecurity sets the security option used by .connect, copy and diff
+func SetDefaultSecurity(s SecurityOption) {
+	_securityMutex.Lock()
+	defer _securityMutex.Unlock()
+	_defaultSecurity = s
+}
+
+func GetDefaultSecurity() SecurityOption {
+	_securityMutex.RLock()
+	defer _securityMutex.RUnlock()
+	return _defaultSecurity
+}
+
+func (s SecurityOption) Validate() error {
+	if (s.CertPath == "") != (s.KeyPath == "") {
+		return errors.New("the cert and the key should be set together")
+	}
+	if s.CAPath == "" && (s.CertPath != "" || len(s.CertAllowedCN) > 0) {
+		return errors.New("the ca is required by the cert, the key and the cert-allowed-cn")
+	}
+	// load the certificates before connecting, or the bad ones are found
+	// after retrying to connect to PD for a long time
+	sec := s.tikvSecurity()
+	_, err := sec.ToTLSConfig()
+	return err
+}
+
+func (s SecurityOption) tikvSecurity() config.Security {
+	return config.NewSecurity(s.CAPath, s.CertPath, s.KeyPath, s.CertAllowedCN)
+}
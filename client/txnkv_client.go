@@ -3,32 +3,62 @@ package client
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/c4pt0r/tcli/utils"
 
 	"github.com/c4pt0r/tcli"
 
-	"github.com/c4pt0r/log"
+	"github.com/tikv/client-go/v2/config"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/kv"
+	"github.com/tikv/client-go/v2/oracle"
 	"github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/txnkv"
 	pd "github.com/tikv/pd/client"
 )
 
-func newTxnKVClient(pdAddr []string) *txnkvClient {
-	client, err := tikv.NewTxnClient(pdAddr)
+// PessimisticLockWaitTime is the max time (in ms) to wait for a lock in
+// pessimistic transactions
+var PessimisticLockWaitTime int64 = 10000
+
+// txnClientMutex serializes the creation of the txn clients, which read the
+// security config from the global config of client-go
+var txnClientMutex sync.Mutex
+
+func newTxnKVClient(pdAddr []string, security SecurityOption, api APIOption) (*txnkvClient, error) {
+	txnClientMutex.Lock()
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Security = security.tikvSecurity()
+	})
+	client, err := txnkv.NewClient(pdAddr,
+		txnkv.WithAPIVersion(api.kvrpcVersion()),
+		txnkv.WithKeyspace(api.Keyspace))
+	txnClientMutex.Unlock()
 	if err != nil {
-		log.F(err)
+		return nil, err
 	}
 	return &txnkvClient{
-		txnClient: client,
+		txnClient: client.KVStore,
 		pdAddr:    pdAddr,
-	}
+		security:  security,
+		api:       api,
+	}, nil
 }
 
 type txnkvClient struct {
 	txnClient *tikv.KVStore
 	pdAddr    []string
+	security  SecurityOption
+	api       APIOption
+
+	// the interactive transaction opened by Begin, nil if there is none
+	mu      sync.Mutex
+	txn     *tikv.KVTxn
+	txnInfo TxnInfo
 }
 
 func (c *txnkvClient) Close() {
@@ -46,54 +76,154 @@ func (c *txnkvClient) GetClusterID() string {
 }
 
 func (c *txnkvClient) GetStores() ([]StoreInfo, error) {
-	var ret []StoreInfo
-	stores, err := c.txnClient.GetPDClient().GetAllStores(context.TODO())
+	return getStores(c.txnClient.GetPDClient())
+}
+
+func (c *txnkvClient) GetPDClient() pd.Client {
+	return c.txnClient.GetPDClient()
+}
+
+func (c *txnkvClient) currentTxn() *tikv.KVTxn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.txn
+}
+
+func (c *txnkvClient) Begin(ctx context.Context, pessimistic bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.txn != nil {
+		return errors.New("a transaction is already open, commit or rollback it first")
+	}
+	tx, err := c.txnClient.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	for _, store := range stores {
-		labels := store.GetLabels()
-		var strLabels []string
-		for _, label := range labels {
-			strLabels = append(strLabels, fmt.Sprintf("%s=%s", label.Key, label.Value))
-		}
-		ret = append(ret, StoreInfo{
-			ID:            fmt.Sprintf("%d", store.GetId()),
-			Version:       store.GetVersion(),
-			Addr:          store.GetAddress(),
-			State:         store.GetState().String(),
-			StatusAddress: store.GetStatusAddress(),
-			Labels:        strings.Join(strLabels, ","),
-		})
+	tx.SetPessimistic(pessimistic)
+	c.txn = tx
+	c.txnInfo = TxnInfo{
+		StartTS:     tx.StartTS(),
+		StartTime:   time.Now(),
+		Pessimistic: pessimistic,
 	}
-	return ret, nil
+	return nil
 }
 
-func (c *txnkvClient) GetPDClient() pd.Client {
-	return c.txnClient.GetPDClient()
+// takeTxn returns the open transaction and detaches it from the client
+func (c *txnkvClient) takeTxn() (*tikv.KVTxn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.txn == nil {
+		return nil, errors.New("no open transaction, use begin to start one")
+	}
+	tx := c.txn
+	c.txn = nil
+	return tx, nil
 }
 
-func (c *txnkvClient) Put(ctx context.Context, kv KV) error {
+func (c *txnkvClient) Commit(ctx context.Context) error {
+	tx, err := c.takeTxn()
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		// release the pessimistic locks if there are
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (c *txnkvClient) Rollback(ctx context.Context) error {
+	tx, err := c.takeTxn()
+	if err != nil {
+		return err
+	}
+	return tx.Rollback()
+}
+
+func (c *txnkvClient) GetTxnInfo() *TxnInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.txn == nil {
+		return nil
+	}
+	info := c.txnInfo
+	return &info
+}
+
+// runInTxn runs f in the open transaction if there is one, keys are locked
+// first if it's pessimistic. Otherwise f runs in a new transaction which is
+// committed after f returns.
+func (c *txnkvClient) runInTxn(ctx context.Context, keys [][]byte, f func(tx *tikv.KVTxn) error) error {
+	if tx := c.currentTxn(); tx != nil {
+		if tx.IsPessimistic() && len(keys) > 0 {
+			// each statement locks the keys with the latest version, as the
+			// keys may be changed by others after the transaction starts
+			forUpdateTS, err := c.txnClient.CurrentTimestamp(oracle.GlobalTxnScope)
+			if err != nil {
+				return err
+			}
+			lockCtx := kv.NewLockCtx(forUpdateTS, PessimisticLockWaitTime, time.Now())
+			if err := tx.LockKeys(ctx, lockCtx, keys...); err != nil {
+				return err
+			}
+		}
+		return f(tx)
+	}
+
 	tx, err := c.txnClient.Begin()
 	if err != nil {
 		return err
 	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit(ctx)
+}
 
-	tx.Set(kv.K, kv.V)
+func (c *txnkvClient) Put(ctx context.Context, kv KV) error {
+	if err := checkNoTTL(ctx); err != nil {
+		return err
+	}
+	return c.runInTxn(ctx, [][]byte{kv.K}, func(tx *tikv.KVTxn) error {
+		return tx.Set(kv.K, kv.V)
+	})
+}
 
-	err = tx.Commit(context.TODO())
+// beginRead starts a transaction for reading, it reads the snapshot at the
+// read timestamp if it's set, see getReadTS
+func (c *txnkvClient) beginRead(ctx context.Context) (*tikv.KVTxn, error) {
+	ts, err := getReadTS(ctx)
 	if err != nil {
-		err = tx.Rollback()
-		if err != nil {
-			return err
+		return nil, err
+	}
+	// reads in the open transaction see its uncommitted writes
+	if tx := c.currentTxn(); tx != nil {
+		if ts != 0 {
+			return nil, errors.New("snapshot read (--as-of or sys.read_ts) is not supported in an open transaction")
 		}
+		return tx, nil
 	}
-	return nil
+	if ts == 0 {
+		return c.txnClient.Begin()
+	}
+	return c.txnClient.Begin(tikv.WithStartTS(ts))
+}
+
+// beginWrite returns the open transaction, or starts a new one at the latest
+// timestamp. the reads for writing never see the snapshot of the read timestamp
+func (c *txnkvClient) beginWrite() (*tikv.KVTxn, error) {
+	if tx := c.currentTxn(); tx != nil {
+		return tx, nil
+	}
+	return c.txnClient.Begin()
 }
 
 func (c *txnkvClient) Scan(ctx context.Context, startKey []byte) (KVS, int, error) {
 	scanOpts := utils.PropFromContext(ctx)
-	tx, err := c.txnClient.Begin()
+	tx, err := c.beginRead(ctx)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -103,10 +233,24 @@ func (c *txnkvClient) Scan(ctx context.Context, startKey []byte) (KVS, int, erro
 	keyOnly := scanOpts.GetBool(tcli.ScanOptKeyOnly, false)
 	if keyOnly || countOnly {
 		tx.GetSnapshot().SetKeyOnly(keyOnly)
+		// the snapshot may be shared by the open transaction
+		defer tx.GetSnapshot().SetKeyOnly(false)
 	}
 	// count only mode will ignore this
 	limit := scanOpts.GetInt(tcli.ScanOptLimit, 100)
-	it, err := tx.Iter(startKey, nil)
+	// nil means no upper bound
+	endKey, err := utils.GetStringLitOpt(scanOpts, tcli.ScanOptEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	// in reverse mode, scan keys in [startKey, endKey) from the end
+	reverse := scanOpts.GetBool(tcli.ScanOptReverse, false)
+	var it tikv.Iterator
+	if reverse {
+		it, err = tx.IterReverse(reverseScanUpperBound(startKey, endKey, strictPrefix), startKey)
+	} else {
+		it, err = tx.Iter(startKey, endKey)
+	}
 	if err != nil {
 		return nil, 0, err
 	}
@@ -122,6 +266,9 @@ func (c *txnkvClient) Scan(ctx context.Context, startKey []byte) (KVS, int, erro
 		if strictPrefix && !bytes.HasPrefix(it.Key(), startKey) {
 			break
 		}
+		if reverse && bytes.Compare(it.Key(), startKey) < 0 {
+			break
+		}
 		// count only will not use limit
 		if !countOnly {
 			ret = append(ret, KV{K: it.Key()[:], V: it.Value()[:]})
@@ -139,21 +286,47 @@ func (c *txnkvClient) Scan(ctx context.Context, startKey []byte) (KVS, int, erro
 }
 
 func (c *txnkvClient) BatchPut(ctx context.Context, kvs []KV) error {
-	tx, err := c.txnClient.Begin()
-	if err != nil {
+	if err := checkNoTTL(ctx); err != nil {
 		return err
 	}
+	keys := make([][]byte, 0, len(kvs))
 	for _, kv := range kvs {
-		err := tx.Set(kv.K[:], kv.V[:])
-		if err != nil {
-			return err
+		keys = append(keys, kv.K)
+	}
+	return c.runInTxn(ctx, keys, func(tx *tikv.KVTxn) error {
+		for _, kv := range kvs {
+			if err := tx.Set(kv.K[:], kv.V[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *txnkvClient) BatchGet(ctx context.Context, keys []Key) (KVS, error) {
+	tx, err := c.beginRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawKeys := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		rawKeys = append(rawKeys, k)
+	}
+	values, err := tx.BatchGet(context.TODO(), rawKeys)
+	if err != nil {
+		return nil, err
+	}
+	var ret KVS
+	for _, k := range keys {
+		if v, ok := values[string(k)]; ok {
+			ret = append(ret, KV{K: k, V: v})
 		}
 	}
-	return tx.Commit(context.Background())
+	return ret, nil
 }
 
 func (c *txnkvClient) Get(ctx context.Context, k Key) (KV, error) {
-	tx, err := c.txnClient.Begin()
+	tx, err := c.beginRead(ctx)
 	if err != nil {
 		return KV{}, err
 	}
@@ -165,84 +338,143 @@ func (c *txnkvClient) Get(ctx context.Context, k Key) (KV, error) {
 }
 
 func (c *txnkvClient) Delete(ctx context.Context, k Key) error {
+	return c.runInTxn(ctx, [][]byte{k}, func(tx *tikv.KVTxn) error {
+		return tx.Delete(k)
+	})
+}
+
+func (c *txnkvClient) GetTTL(ctx context.Context, k Key) (time.Duration, error) {
+	return 0, errors.New("ttl is only supported in raw mode")
+}
+
+func (c *txnkvClient) CompareAndSwap(ctx context.Context, k Key, oldValue, newValue []byte) ([]byte, bool, error) {
+	if err := checkNoTTL(ctx); err != nil {
+		return nil, false, err
+	}
+	var prev []byte
+	var swapped bool
+	// in the open transaction, read-check-write, the key is locked first
+	// if the transaction is pessimistic
+	if tx := c.currentTxn(); tx != nil {
+		err := c.runInTxn(ctx, [][]byte{k}, func(tx *tikv.KVTxn) error {
+			v, err := tx.Get(ctx, k)
+			if err != nil && !tikverr.IsErrNotFound(err) {
+				return err
+			}
+			prev = v
+			if swapped = casMatch(prev, oldValue); swapped {
+				return tx.Set(k, newValue)
+			}
+			return nil
+		})
+		return prev, swapped, err
+	}
+
+	// otherwise lock the key with the latest value in a pessimistic transaction,
+	// so it can't be changed by others between the check and the write
 	tx, err := c.txnClient.Begin()
 	if err != nil {
-		return err
+		return nil, false, err
+	}
+	tx.SetPessimistic(true)
+	forUpdateTS, err := c.txnClient.CurrentTimestamp(oracle.GlobalTxnScope)
+	if err != nil {
+		return nil, false, err
+	}
+	lockCtx := kv.NewLockCtx(forUpdateTS, PessimisticLockWaitTime, time.Now())
+	lockCtx.InitReturnValues(1)
+	if err := tx.LockKeys(ctx, lockCtx, k); err != nil {
+		tx.Rollback()
+		return nil, false, err
 	}
-	tx.Delete(k)
-	return tx.Commit(context.Background())
+	// empty values are not allowed in txn mode, so it means not exists
+	if v, _ := lockCtx.GetValueNotLocked(k); len(v) > 0 {
+		prev = v
+	}
+	if !casMatch(prev, oldValue) {
+		return prev, false, tx.Rollback()
+	}
+	if err := tx.Set(k, newValue); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+	return prev, true, nil
 }
 
 // return lastKey, delete count, error
 func (c *txnkvClient) DeletePrefix(ctx context.Context, prefix Key, limit int) (Key, int, error) {
-	tx, err := c.txnClient.Begin()
+	tx, err := c.beginWrite()
 	if err != nil {
 		return nil, 0, err
 	}
 
+	var lastKey Key
+	count := 0
+	start := []byte(prefix)
+	for limit > 0 {
+		// TODO batch size shoule not be fixed
+		batch, err := c.scanKeysWithPrefix(tx, start, prefix, min(limit, 1000))
+		if err != nil {
+			return lastKey, count, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		// the iterator is closed before deleting, the deletions may go to
+		// the same transaction
+		if err := c.BatchDelete(ctx, batch); err != nil {
+			return lastKey, count, err
+		}
+		count += len(batch)
+		limit -= len(batch)
+		lastKey = batch[len(batch)-1].K
+		start = utils.NextKey(lastKey)
+	}
+	return lastKey, count, nil
+}
+
+// scanKeysWithPrefix returns at most limit keys with prefix from start key
+func (c *txnkvClient) scanKeysWithPrefix(tx *tikv.KVTxn, start, prefix []byte, limit int) ([]KV, error) {
 	tx.GetSnapshot().SetKeyOnly(true)
+	defer tx.GetSnapshot().SetKeyOnly(false)
 
-	it, err := tx.Iter(prefix, nil)
+	it, err := tx.Iter(start, nil)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer it.Close()
-
-	var lastKey KV
-	count := 0
-
-	var batch []KV
-
-	for it.Valid() && limit > 0 {
+	var keys []KV
+	for it.Valid() && len(keys) < limit {
 		if !bytes.HasPrefix(it.Key(), prefix) {
 			break
 		}
-		lastKey.K = it.Key()[:]
-		batch = append(batch, KV{K: it.Key()[:]})
-		// TODO batch size shoule not be fixed
-		if len(batch) == 1000 {
-			// do delete
-			if err := c.BatchDelete(ctx, batch); err != nil {
-				return lastKey.K, count, err
-			}
-			count += len(batch)
-			// reset batch
-			batch = nil
-		}
-		limit--
-		it.Next()
-	}
-	if len(batch) > 0 {
-		if err := c.BatchDelete(ctx, batch); err != nil {
-			return nil, count, err
+		keys = append(keys, KV{K: append([]byte{}, it.Key()...)})
+		if err := it.Next(); err != nil {
+			return nil, err
 		}
-		count += len(batch)
 	}
-	return lastKey.K, count, nil
+	return keys, nil
 }
 
 func (c *txnkvClient) BatchDelete(ctx context.Context, kvs []KV) error {
-	tx, err := c.txnClient.Begin()
-	if err != nil {
-		return err
-	}
+	keys := make([][]byte, 0, len(kvs))
 	for _, kv := range kvs {
-		err := tx.Delete(kv.K)
-		if err != nil {
-			return err
-		}
+		keys = append(keys, kv.K)
 	}
-	return tx.Commit(context.Background())
+	return c.runInTxn(ctx, keys, func(tx *tikv.KVTxn) error {
+		for _, kv := range kvs {
+			if err := tx.Delete(kv.K); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (c *txnkvClient) GetPDs() ([]PDInfo, error) {
-	pds, err := c.txnClient.GetPDClient().GetAllMembers(context.TODO())
-	if err != nil {
-		return nil, err
-	}
-	var ret []PDInfo
-	for _, pd := range pds {
-		ret = append(ret, PDInfo{Name: pd.Name, ClientURLs: pd.GetClientUrls()})
-	}
-	return ret, nil
+	return getPDs(c.txnClient.GetPDClient())
 }
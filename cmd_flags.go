@@ -6,6 +6,11 @@ var (
 	ScanOptCountOnly    string = "count-only"
 	ScanOptLimit        string = "limit"
 	ScanOptStrictPrefix string = "strict-prefix"
+	ScanOptEnd          string = "end"
+	ScanOptReverse      string = "reverse"
+	ScanOptAsOf         string = "as-of"
+	// number of workers of the parallel scans in count, backup and delp
+	ScanOptConcurrency string = "concurrency"
 )
 
 // for completer to work, keyword list
@@ -14,6 +19,10 @@ var ScanOptsKeywordList = []string{
 	ScanOptCountOnly,
 	ScanOptLimit,
 	ScanOptStrictPrefix,
+	ScanOptEnd,
+	ScanOptReverse,
+	ScanOptAsOf,
+	ScanOptConcurrency,
 }
 
 ///////////////////// end of scan options ///////////////
@@ -39,11 +48,24 @@ var DeleteOptsKeywordList = []string{
 var (
 	LoadFileOptBatchSize string = "batch-size"
 	LoadFileoptSkipRows  string = "skip-rows"
+	// same as PutOptTTL, it's passed to BatchPut
+	LoadFileOptTTL         string = "ttl"
+	LoadFileOptConcurrency string = "concurrency"
+	LoadFileOptRateLimit   string = "rate-limit"
+	LoadFileOptMaxRetries  string = "max-retries"
+	LoadFileOptKeyTemplate string = "key-template"
+	LoadFileOptValue       string = "value"
 )
 
 var LoadFileOptsKeywordList = []string{
 	LoadFileOptBatchSize,
 	LoadFileoptSkipRows,
+	LoadFileOptTTL,
+	LoadFileOptConcurrency,
+	LoadFileOptRateLimit,
+	LoadFileOptMaxRetries,
+	LoadFileOptKeyTemplate,
+	LoadFileOptValue,
 }
 
 //////////////// end of loadcsv options ///////////////
@@ -51,10 +73,162 @@ var LoadFileOptsKeywordList = []string{
 ///////////////// backup options /////////////////////
 var (
 	BackupOptBatchSize string = "batch-size"
+	BackupOptResume    string = "resume"
+	BackupOptFormat    string = "format"
+	BackupOptCompress  string = "compress"
 )
 
 var BackupOptsKeywordList = []string{
 	BackupOptBatchSize,
+	BackupOptResume,
+	BackupOptFormat,
+	BackupOptCompress,
 }
 
 //////////////// end of backup options ///////////////
+
+///////////////// restore options /////////////////////
+var (
+	RestoreOptBatchSize string = "batch-size"
+	RestoreOptDryRun    string = "dry-run"
+	RestoreOptOverwrite string = "overwrite"
+	RestoreOptVerify    string = "verify"
+)
+
+var RestoreOptsKeywordList = []string{
+	RestoreOptBatchSize,
+	RestoreOptDryRun,
+	RestoreOptOverwrite,
+	RestoreOptVerify,
+}
+
+//////////////// end of restore options ///////////////
+
+///////////////// copy options /////////////////////
+var (
+	CopyOptToPD         string = "to-pd"
+	CopyOptToMode       string = "to-mode"
+	CopyOptRenamePrefix string = "rename-prefix"
+	CopyOptBatchSize    string = "batch-size"
+	CopyOptDryRun       string = "dry-run"
+	CopyOptResume       string = "resume"
+	CopyOptCheckpoint   string = "checkpoint"
+	CopyOptMaxRetries   string = "max-retries"
+)
+
+var CopyOptsKeywordList = []string{
+	CopyOptToPD,
+	CopyOptToMode,
+	CopyOptRenamePrefix,
+	CopyOptBatchSize,
+	CopyOptDryRun,
+	CopyOptResume,
+	CopyOptCheckpoint,
+	CopyOptMaxRetries,
+}
+
+//////////////// end of copy options ///////////////
+
+///////////////// diff options /////////////////////
+var (
+	DiffOptAgainstPD   string = "against-pd"
+	DiffOptAgainstMode string = "against-mode"
+	DiffOptOutput      string = "output"
+	DiffOptPatchFile   string = "patch-file"
+	DiffOptLimit       string = "limit"
+)
+
+var DiffOptsKeywordList = []string{
+	DiffOptAgainstPD,
+	DiffOptAgainstMode,
+	DiffOptOutput,
+	DiffOptPatchFile,
+	DiffOptLimit,
+}
+
+//////////////// end of diff options ///////////////
+
+///////////////// checksum options /////////////////////
+var (
+	ChecksumOptStart string = "start"
+)
+
+var ChecksumOptsKeywordList = []string{
+	ChecksumOptStart,
+}
+
+//////////////// end of checksum options ///////////////
+
+///////////////// regions options /////////////////////
+var (
+	RegionsOptLimit string = "limit"
+)
+
+var RegionsOptsKeywordList = []string{
+	RegionsOptLimit,
+}
+
+//////////////// end of regions options ///////////////
+
+///////////////// put options //////////////////////
+var (
+	PutOptIfNotExists string = "if-not-exists"
+	PutOptTTL         string = "ttl"
+)
+
+var PutOptsKeywordList = []string{
+	PutOptIfNotExists,
+	PutOptTTL,
+}
+
+//////////////// end of put options ///////////////
+
+///////////////// mget options //////////////////////
+var (
+	MgetOptFromFile       string = "from-file"
+	MgetOptIncludeMissing string = "include-missing"
+)
+
+var MgetOptsKeywordList = []string{
+	MgetOptFromFile,
+	MgetOptIncludeMissing,
+}
+
+//////////////// end of mget options ///////////////
+
+///////////////// begin options //////////////////////
+var (
+	TxnOptPessimistic string = "pessimistic"
+)
+
+var TxnOptsKeywordList = []string{
+	TxnOptPessimistic,
+}
+
+//////////////// end of begin options ///////////////
+
+///////////////// connect options //////////////////////
+var (
+	ConnectOptMode       string = "mode"
+	ConnectOptAPIVersion string = "api-version"
+	ConnectOptKeyspace   string = "keyspace"
+)
+
+var ConnectOptsKeywordList = []string{
+	ConnectOptMode,
+	ConnectOptAPIVersion,
+	ConnectOptKeyspace,
+}
+
+//////////////// end of connect options ///////////////
+
+///////////////// keyspaces options //////////////////////
+var (
+	KeyspacesOptLimit string = "limit"
+)
+
+var KeyspacesOptsKeywordList = []string{
+	KeyspacesOptLimit,
+}
+
+//////////////// end of keyspaces options ///////////////
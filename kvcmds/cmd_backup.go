@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/c4pt0r/tcli"
 	"github.com/c4pt0r/tcli/client"
@@ -20,7 +24,7 @@ type BackupCmd struct{}
 func (c BackupCmd) Name() string    { return "backup" }
 func (c BackupCmd) Alias() []string { return []string{"backup"} }
 func (c BackupCmd) Help() string {
-	return "dumps kv pairs to a csv file"
+	return "dumps kv pairs to a file in csv, jsonl or binary format"
 }
 
 func (c BackupCmd) LongHelp() string {
@@ -31,6 +35,18 @@ Usage:
 	backup <prefix> <outfile> <opts>
 Options:
 	--batch-size=<size>, default 1000
+	--end=<end key>, backup kvs in [start key, end key), the first argument is used as start key
+	--as-of=<tso|RFC3339 time>, backup the snapshot at the timestamp, txn mode only
+	--concurrency=<n>, number of regions scanned in parallel, default: 8
+	--format=csv|jsonl|binary, format of the backup file, default: csv
+		csv: Key,Value header, keys and values in hex string literals
+		jsonl: a json object per line, keys and values in base64
+		binary: length-prefixed key/value frames, with a header and a trailing checksum
+	--compress=gzip|zstd, compress the backup file, default: no compression
+	--resume, continue the failed backup to outfile from its checkpoint file <outfile>.checkpoint
+Description:
+	when the backup is done, the checksum of the kv pairs (see checksum) is written to <outfile>.checksum,
+	which can be checked by restore --verify.
 Example:
 	# backup all kvs with prefix "t_" to csv file
 	backup "t_" backup.csv --batch-size=5000
@@ -38,6 +54,15 @@ Example:
 	# backup all kvs to csv file
 	backup * backup.csv
 	backup $head  backup.csv
+
+	# backup all kvs in ["a", "b") to csv file
+	backup "a" backup.csv --end="b"
+
+	# backup all kvs with prefix "t_" to a zstd compressed binary file
+	backup "t_" backup.bin.zst --format=binary --compress=zstd
+
+	# continue the failed backup
+	backup "t_" backup.csv --resume
 `)
 	return buf.String()
 }
@@ -51,63 +76,290 @@ func writeKvsToCsvFile(w *csv.Writer, kvs client.KVS) error {
 		}
 	}
 	w.Flush()
+	return w.Error()
+}
+
+// backupCheckpoint is saved next to the backup file after each batch, so a
+// failed backup can be resumed from the last written key
+type backupCheckpoint struct {
+	// the scan range of the backup, in string literal
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// the last key written, in string literal, empty if nothing is written
+	LastKey string `json:"last_key"`
+	Batches int    `json:"batches"`
+	Rows    int    `json:"rows"`
+	// size of the backup file when the checkpoint is saved, the data after
+	// it is discarded when resuming
+	Bytes int64 `json:"bytes"`
+	// format of the backup file, empty means csv
+	Format string `json:"format,omitempty"`
+	// checksum of the binary format when the checkpoint is saved
+	Checksum uint64 `json:"checksum,omitempty"`
+	// checksum of the kv pairs written
+	KVChecksum kvChecksum `json:"kv_checksum"`
+}
+
+func backupCheckpointFile(outputFile string) string {
+	return outputFile + ".checkpoint"
+}
+
+// backupChecksum is saved next to the backup file when the backup is done,
+// it's checked by restore --verify
+type backupChecksum struct {
+	// the range of the backup, in string literal
+	Start string `json:"start"`
+	End   string `json:"end"`
+	kvChecksum
+}
+
+func backupChecksumFile(outputFile string) string {
+	return outputFile + ".checksum"
+}
+
+func loadBackupCheckpoint(filename string) (*backupCheckpoint, error) {
+	cp := &backupCheckpoint{}
+	if err := loadJSONFile(filename, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// loadJSONFile reads the checkpoint or the checksum file to v
+func loadJSONFile(filename string, v interface{}) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid file %s: %s", filename, err)
+	}
 	return nil
 }
 
+func (cp *backupCheckpoint) save(filename string) error {
+	return saveJSONFile(filename, cp)
+}
+
+// saveJSONFile writes v to a temp file and renames it, so the checkpoint or
+// the checksum file is never half written
+func saveJSONFile(filename string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmpFile := filename + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filename)
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// openBackupFile creates the backup file, or opens it for appending if the
+// backup is resumed, the data after the checkpoint is discarded.
+func openBackupFile(outputFile string, cp *backupCheckpoint, resume bool) (*os.File, error) {
+	if !resume {
+		_, err := os.Stat(outputFile)
+		if !os.IsNotExist(err) {
+			return nil, errors.New("Backup file already exists, use --resume to continue a failed backup")
+		}
+		return os.Create(outputFile)
+	}
+	fp, err := os.OpenFile(outputFile, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := fp.Truncate(cp.Bytes); err != nil {
+		fp.Close()
+		return nil, err
+	}
+	if _, err := fp.Seek(cp.Bytes, io.SeekStart); err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return fp, nil
+}
+
 func (c BackupCmd) Handler() func(ctx context.Context) {
 	return func(ctx context.Context) {
 		utils.OutputWithElapse(func() error {
 			ic := utils.ExtractIshellContext(ctx)
-			if len(ic.Args) < 2 {
+			// parse args and options from raw args to keep the string literals
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if len(args) < 3 { // args[0] is the command name
 				utils.Print(c.LongHelp())
 				return nil
 			}
-			prefix, err := utils.GetStringLit(ic.Args[0])
+			prefix, err := utils.GetStringLit(args[1])
 			if err != nil {
 				return err
 			}
-			outputFile := ic.Args[1]
-			_, err = os.Stat(outputFile)
-			if !os.IsNotExist(err) {
-				return errors.New("Backup file already exists")
+			outputFile := args[2]
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
 			}
-			fp, err := os.Create(outputFile)
+			start, end, err := getScanRange(prefix, opt)
 			if err != nil {
 				return err
 			}
-			csvWriter := csv.NewWriter(fp)
-			defer csvWriter.Flush()
-			// Write first line
-			csvWriter.Write([]string{"Key", "Value"})
 
-			opt := properties.NewProperties()
-			if len(ic.Args) > 2 {
-				err := utils.SetOptByString(ic.Args[2:], opt)
+			format := opt.GetString(tcli.BackupOptFormat, dumpFormatCSV)
+			compress := opt.GetString(tcli.BackupOptCompress, "")
+			if err := checkDumpFormat(format, compress); err != nil {
+				return err
+			}
+			checkpointFile := backupCheckpointFile(outputFile)
+			resume := opt.GetBool(tcli.BackupOptResume, false)
+			// a compressed stream can't be truncated at the checkpoint
+			saveCheckpoint := len(compress) == 0
+			if resume && !saveCheckpoint {
+				return errors.New("--resume is not supported for compressed backups")
+			}
+			cp := &backupCheckpoint{
+				Start:  utils.Bytes2StrLit(start),
+				End:    utils.Bytes2StrLit(end),
+				Format: format,
+			}
+			if resume {
+				cp, err = loadBackupCheckpoint(checkpointFile)
 				if err != nil {
 					return err
 				}
+				if cp.Start != utils.Bytes2StrLit(start) || cp.End != utils.Bytes2StrLit(end) {
+					return fmt.Errorf("the checkpoint is for the backup of [%s, %s), not the same range", cp.Start, cp.End)
+				}
+				if len(cp.Format) == 0 {
+					cp.Format = dumpFormatCSV
+				}
+				if cp.Format != format {
+					return fmt.Errorf("the checkpoint is for the backup in %s format, not %s", cp.Format, format)
+				}
+				if len(cp.LastKey) > 0 {
+					lastKey, err := utils.GetStringLit(cp.LastKey)
+					if err != nil {
+						return err
+					}
+					start = utils.NextKey(lastKey)
+				}
+				utils.PrintProgress(fmt.Sprintf("Resume from checkpoint, rows: %d, last key: %s", cp.Rows, cp.LastKey))
 			}
-			opt.Set(tcli.ScanOptLimit, opt.GetString(tcli.BackupOptBatchSize, "1000"))
-			if bytes.Compare(prefix, []byte("\x00")) != 0 && string(prefix) != "*" {
-				opt.Set(tcli.ScanOptStrictPrefix, "true")
+
+			fp, err := openBackupFile(outputFile, cp, resume)
+			if err != nil {
+				return err
 			}
-			kvs, cnt, err := client.GetTiKVClient().Scan(utils.ContextWithProp(context.TODO(), opt), prefix)
+			defer fp.Close()
+			cw := &countingWriter{w: fp, n: cp.Bytes}
+			var w io.Writer = cw
+			compressor, err := newCompressWriter(compress, cw)
 			if err != nil {
 				return err
 			}
-			for cnt > 0 {
+			if compressor != nil {
+				w = compressor
+			}
+			dw, err := newDumpWriter(format, w, resume, cp.Checksum)
+			if err != nil {
+				return err
+			}
+			// saves the checkpoint after the data is flushed
+			checkpoint := func() error {
+				if !saveCheckpoint {
+					return nil
+				}
+				if err := dw.Flush(); err != nil {
+					return err
+				}
+				cp.Bytes = cw.n
+				if bw, ok := dw.(*binaryDumpWriter); ok {
+					cp.Checksum = bw.checksum
+				}
+				return cp.save(checkpointFile)
+			}
+			if !resume {
+				if err := checkpoint(); err != nil {
+					return err
+				}
+			}
+
+			// regions are scanned in parallel, and written in key order
+			ctx, stop := utils.WithInterrupt(context.TODO())
+			defer stop()
+			tt := time.Now()
+			batches, rows, bytesWritten := 0, 0, cw.n
+			err = client.ParallelScan(ctx, client.GetTiKVClient(), start, end, client.ParallelScanOpt{
+				Concurrency: opt.GetInt(tcli.ScanOptConcurrency, defaultScanConcurrency),
+				BatchSize:   opt.GetInt(tcli.BackupOptBatchSize, 1000),
+				Ordered:     true,
+				ScanOpt:     opt,
+			}, func(kvs client.KVS) error {
 				// write file
-				if err := writeKvsToCsvFile(csvWriter, kvs); err != nil {
+				if err := dw.WriteKVs(kvs); err != nil {
 					return err
 				}
-				lastKey := utils.NextKey(kvs[len(kvs)-1].K)
-				utils.Print("Write a batch, batch size:", cnt, "Last key:", kvs[len(kvs)-1].K)
-				// run next batch
-				kvs, cnt, err = client.GetTiKVClient().Scan(utils.ContextWithProp(context.TODO(), opt), lastKey)
-				if err != nil {
+				batches++
+				rows += len(kvs)
+				cp.Batches++
+				cp.Rows += len(kvs)
+				cp.KVChecksum.update(kvs)
+				cp.LastKey = utils.Bytes2StrLit(kvs[len(kvs)-1].K)
+				if err := checkpoint(); err != nil {
 					return err
 				}
+				utils.PrintProgress("Write a batch, batch size:", len(kvs), "Last key:", kvs[len(kvs)-1].K)
+				return nil
+			})
+			if err != nil {
+				if saveCheckpoint {
+					return fmt.Errorf("%s, use --resume to continue the backup", err)
+				}
+				return err
+			}
+			if err := dw.Close(); err != nil {
+				return err
+			}
+			if compressor != nil {
+				if err := compressor.Close(); err != nil {
+					return err
+				}
+			}
+			// the backup is done
+			if err := saveJSONFile(backupChecksumFile(outputFile), &backupChecksum{
+				Start:      cp.Start,
+				End:        cp.End,
+				kvChecksum: cp.KVChecksum,
+			}); err != nil {
+				return err
+			}
+			os.Remove(checkpointFile)
+
+			result := []client.KV{
+				{K: []byte("Batches"), V: []byte(fmt.Sprintf("%d", batches))},
+				{K: []byte("Rows"), V: []byte(fmt.Sprintf("%d", rows))},
+				{K: []byte("Bytes"), V: []byte(fmt.Sprintf("%d", cw.n-bytesWritten))},
+				{K: []byte("Checksum"), V: []byte(cp.KVChecksum.String())},
+				{K: []byte("Elapsed"), V: []byte(time.Since(tt).Round(time.Millisecond).String())},
+			}
+			if resume {
+				result = append(result,
+					client.KV{K: []byte("Total Rows"), V: []byte(fmt.Sprintf("%d", cp.Rows))},
+					client.KV{K: []byte("Total Bytes"), V: []byte(fmt.Sprintf("%d", cw.n))},
+				)
 			}
+			client.KVS(result).Print()
 			return nil
 		})
 	}
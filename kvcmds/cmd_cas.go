@@ -0,0 +1,78 @@
+package kvcmds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+)
+
+type CasCmd struct{}
+
+var _ tcli.Cmd = CasCmd{}
+
+func (c CasCmd) Name() string    { return "cas" }
+func (c CasCmd) Alias() []string { return []string{"cas"} }
+func (c CasCmd) Help() string {
+	return `compare and swap, set the key to new value only if its value is unchanged`
+}
+
+func (c CasCmd) LongHelp() string {
+	s := c.Help()
+	s += `
+Usage:
+	cas <key> <expected value> <new value>
+Description:
+	raw mode uses RawKV CompareAndSwap, txn mode locks the key in a pessimistic
+	transaction, or checks it in the open transaction.
+	use "put <key> <value> --if-not-exists" if the key is expected not to exist.
+Examples:
+	cas "a" "old" "new"
+	cas h'0001' h'ff' h'fe'
+`
+	return s
+}
+
+// printCASResult prints whether the swap happened and the current value
+func printCASResult(k, prev, newValue []byte, swapped bool) {
+	cur := prev
+	if swapped {
+		cur = newValue
+	}
+	kvs := []client.KV{
+		{K: []byte("Key"), V: k},
+		{K: []byte("Swapped"), V: []byte(fmt.Sprintf("%t", swapped))},
+		{K: []byte("Exists"), V: []byte(fmt.Sprintf("%t", cur != nil))},
+		{K: []byte("Current Value"), V: cur},
+	}
+	client.KVS(kvs).Print()
+}
+
+func (c CasCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			if len(ic.Args) < 3 {
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			var lits [][]byte
+			for _, s := range ic.RawArgs[1:4] {
+				lit, err := utils.GetStringLit(s)
+				if err != nil {
+					return err
+				}
+				lits = append(lits, lit)
+			}
+			k, expected, newValue := lits[0], lits[1], lits[2]
+			prev, swapped, err := client.GetTiKVClient().CompareAndSwap(context.TODO(), k, expected, newValue)
+			if err != nil {
+				return err
+			}
+			printCASResult(k, prev, newValue, swapped)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package kvcmds
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/cespare/xxhash/v2"
+	"github.com/magiconair/properties"
+)
+
+var _ tcli.Cmd = ChecksumCmd{}
+
+type ChecksumCmd struct{}
+
+func (c ChecksumCmd) Name() string    { return "checksum" }
+func (c ChecksumCmd) Alias() []string { return []string{"checksum"} }
+func (c ChecksumCmd) Help() string {
+	return "compute the checksum of the kv pairs with prefix or in a range"
+}
+
+func (c ChecksumCmd) LongHelp() string {
+	var buf bytes.Buffer
+	buf.WriteString(c.Help())
+	buf.WriteString(`
+Usage:
+	checksum <prefix> <opts>
+	checksum --start=<start key> --end=<end key> <opts>
+Options:
+	--start=<start key>, checksum kvs in [start key, end key), no prefix is needed
+	--end=<end key>, checksum kvs in [start key, end key), empty means no upper bound
+	--as-of=<tso|RFC3339 time>, checksum the snapshot at the timestamp, txn mode only
+	--concurrency=<n>, number of regions scanned in parallel, default: 8
+Description:
+	the checksum is the sum (mod 2^64) of the xxhash64 of each kv pair, it doesn't depend on
+	the order of the kv pairs, so the regions are scanned in parallel. the checksum, the number
+	of kv pairs and their total bytes of the same data are always the same, in any cluster.
+	backup writes the checksum to <outfile>.checksum, which is checked by restore --verify.
+Example:
+	checksum "t_"
+	checksum *
+	checksum --start="a" --end="b"
+`)
+	return buf.String()
+}
+
+// kvChecksum is the checksum of a set of kv pairs, merging the checksums of
+// any partition of the set results in the same value. the xxhash64 of the kv
+// pairs are added up, so the changes of different pairs can't cancel out each
+// other as they do with xor-ed crc
+type kvChecksum struct {
+	Checksum uint64 `json:"checksum"`
+	Count    int64  `json:"count"`
+	Bytes    int64  `json:"bytes"`
+}
+
+func (ck *kvChecksum) update(kvs client.KVS) {
+	var buf []byte
+	d := xxhash.New()
+	for _, kv := range kvs {
+		// the key length makes the boundary of key and value unambiguous
+		buf = binary.AppendUvarint(buf[:0], uint64(len(kv.K)))
+		d.Reset()
+		d.Write(buf)
+		d.Write(kv.K)
+		d.Write(kv.V)
+		ck.Checksum += d.Sum64()
+		ck.Count++
+		ck.Bytes += int64(len(kv.K) + len(kv.V))
+	}
+}
+
+func (ck *kvChecksum) merge(other *kvChecksum) {
+	ck.Checksum += other.Checksum
+	ck.Count += other.Count
+	ck.Bytes += other.Bytes
+}
+
+func (ck *kvChecksum) equal(other *kvChecksum) bool {
+	return *ck == *other
+}
+
+func (ck *kvChecksum) String() string {
+	return fmt.Sprintf("0x%016x", ck.Checksum)
+}
+
+func (ck *kvChecksum) kvs(prefix string) []client.KV {
+	return []client.KV{
+		{K: []byte(prefix + "Checksum"), V: []byte(ck.String())},
+		{K: []byte(prefix + "Count"), V: []byte(fmt.Sprintf("%d", ck.Count))},
+		{K: []byte(prefix + "Bytes"), V: []byte(fmt.Sprintf("%d", ck.Bytes))},
+	}
+}
+
+// checksumRange computes the checksum of the kv pairs in [start, end) of c,
+// the regions are scanned in parallel
+func checksumRange(ctx context.Context, c client.Client, start, end []byte, scanOpt *properties.Properties) (*kvChecksum, error) {
+	var (
+		mu  sync.Mutex
+		ret kvChecksum
+	)
+	err := client.ParallelScan(ctx, c, start, end, client.ParallelScanOpt{
+		Concurrency: scanOpt.GetInt(tcli.ScanOptConcurrency, defaultScanConcurrency),
+		BatchSize:   1000,
+		ScanOpt:     scanOpt,
+	}, func(kvs client.KVS) error {
+		var ck kvChecksum
+		ck.update(kvs)
+		mu.Lock()
+		ret.merge(&ck)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func (c ChecksumCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			// parse args and options from raw args to keep the string literals
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			var start, end []byte
+			var err error
+			if _, ok := opt.Get(tcli.ChecksumOptStart); ok {
+				if start, err = utils.GetStringLitOpt(opt, tcli.ChecksumOptStart); err != nil {
+					return err
+				}
+				if end, err = utils.GetStringLitOpt(opt, tcli.ScanOptEnd); err != nil {
+					return err
+				}
+				if len(start) == 0 {
+					start = []byte("\x00")
+				}
+			} else {
+				if len(args) < 2 { // args[0] is the command name
+					utils.Print(c.LongHelp())
+					return nil
+				}
+				prefix, err := utils.GetStringLit(args[1])
+				if err != nil {
+					return err
+				}
+				if start, end, err = getScanRange(prefix, opt); err != nil {
+					return err
+				}
+			}
+			if len(end) > 0 && bytes.Compare(start, end) >= 0 {
+				return errors.New("the start key should be less than the end key")
+			}
+
+			ctx, stop := utils.WithInterrupt(context.TODO())
+			defer stop()
+			ck, err := checksumRange(ctx, client.GetTiKVClient(), start, end, opt)
+			if err != nil {
+				return err
+			}
+			client.KVS(ck.kvs("")).Print()
+			return nil
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package kvcmds
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/magiconair/properties"
+)
+
+func TestKVChecksum(t *testing.T) {
+	sum := func(kvs client.KVS) kvChecksum {
+		var ck kvChecksum
+		ck.update(kvs)
+		return ck
+	}
+	kv := func(k, v string) client.KV {
+		return client.KV{K: []byte(k), V: []byte(v)}
+	}
+	base := sum(client.KVS{kv("a", "1"), kv("b", "2")})
+
+	cases := []struct {
+		name string
+		kvs  client.KVS
+		same bool
+	}{
+		{"same", client.KVS{kv("a", "1"), kv("b", "2")}, true},
+		{"order", client.KVS{kv("b", "2"), kv("a", "1")}, true},
+		{"value changed", client.KVS{kv("a", "1"), kv("b", "3")}, false},
+		{"boundary of key and value", client.KVS{kv("a1", ""), kv("b", "2")}, false},
+		{"swapped values", client.KVS{kv("a", "2"), kv("b", "1")}, false},
+		// the changes of a linear checksum like crc cancel out
+		{"two changes", client.KVS{kv("a", "0"), kv("b", "3")}, false},
+		{"duplicated", client.KVS{kv("a", "1"), kv("a", "1"), kv("b", "2")}, false},
+	}
+	for _, tc := range cases {
+		ck := sum(tc.kvs)
+		if ck.equal(&base) != tc.same {
+			t.Errorf("%s: got %v, base %v, want same: %v", tc.name, ck, base, tc.same)
+		}
+	}
+
+	// the checksum of the partitions are merged into the same one
+	var merged kvChecksum
+	for _, kvs := range []client.KVS{{kv("b", "2")}, nil, {kv("a", "1")}} {
+		ck := sum(kvs)
+		merged.merge(&ck)
+	}
+	if !merged.equal(&base) {
+		t.Fatalf("got merged %+v, want %+v", merged, base)
+	}
+	if base.Count != 2 || base.Bytes != 4 {
+		t.Fatalf("got count %d, bytes %d, want 2, 4", base.Count, base.Bytes)
+	}
+}
+
+func TestChecksumRange(t *testing.T) {
+	c, err := client.NewClient(nil, "mem", client.SecurityOption{}, client.APIOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var kvs client.KVS
+	for i := 0; i < 2500; i++ {
+		kvs = append(kvs, client.KV{K: []byte(fmt.Sprintf("k%04d", i)), V: []byte(fmt.Sprintf("v%d", i))})
+	}
+	if err := c.BatchPut(context.TODO(), kvs); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		start, end string
+		kvs        client.KVS
+	}{
+		{"all", "", "", kvs},
+		{"range", "k0100", "k1100", kvs[100:1100]},
+		{"empty", "x", "", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scanOpt := properties.NewProperties()
+			scanOpt.Set(tcli.ScanOptConcurrency, "4")
+			got, err := checksumRange(context.TODO(), c, []byte(tc.start), []byte(tc.end), scanOpt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want kvChecksum
+			want.update(tc.kvs)
+			if !got.equal(&want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
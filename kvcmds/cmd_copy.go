@@ -0,0 +1,356 @@
+package kvcmds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+)
+
+var _ tcli.Cmd = CopyCmd{}
+
+type CopyCmd struct{}
+
+func (c CopyCmd) Name() string    { return "copy" }
+func (c CopyCmd) Alias() []string { return []string{"cp"} }
+func (c CopyCmd) Help() string {
+	return "copy kv pairs to another TiKV cluster"
+}
+
+func (c CopyCmd) LongHelp() string {
+	var buf bytes.Buffer
+	buf.WriteString(c.Help())
+	buf.WriteString(`
+Usage:
+	copy <prefix> --to-pd=<addr> <opts>
+Alias:
+	cp
+Options:
+	--to-pd=<addr>, PD address of the destination cluster, comma separated for multiple addresses, connected with the TLS flags (--ca, --cert, --key) and the API flags (--api-version, --keyspace) of tcli
+	--to-mode=raw|txn, TiKV API mode of the destination cluster, default: the mode of the current client
+	--rename-prefix=<old>:<new>, replace the prefix <old> of the keys with <new> in the destination
+	--end=<end key>, copy kvs in [start key, end key), the first argument is used as start key
+	--as-of=<tso|RFC3339 time>, copy the snapshot at the timestamp, txn mode only
+	--batch-size=<size>, default 1000
+	--concurrency=<n>, number of regions scanned in parallel, default: 8
+	--max-retries=<n>, how many times to retry a batch on write conflicts and region errors, default: 3
+	--dry-run, only count the keys to copy and the keys already existing in the destination
+	--checkpoint=<file>, the checkpoint file saved after each batch, default: tcli-copy.checkpoint
+	--resume, continue the failed copy from the checkpoint file
+Description:
+	the batches are scanned from the current cluster and written to the destination in key order,
+	when it's done, the keys in the source range and the destination range are counted and compared.
+Example:
+	# copy all kvs with prefix "t_" to another cluster
+	copy "t_" --to-pd=10.0.1.1:2379,10.0.1.2:2379
+
+	# copy all kvs with prefix "staging/" to the raw mode cluster, as "prod/"
+	copy "staging/" --to-pd=10.0.1.1:2379 --to-mode=raw --rename-prefix=staging/:prod/
+
+	# continue the failed copy
+	copy "t_" --to-pd=10.0.1.1:2379 --resume
+`)
+	return buf.String()
+}
+
+const defaultCopyCheckpointFile = "tcli-copy.checkpoint"
+
+// copyCheckpoint is saved after each batch, so a failed copy can be resumed
+// from the last copied key
+type copyCheckpoint struct {
+	// the scan range of the copy, in string literal
+	Start        string `json:"start"`
+	End          string `json:"end"`
+	ToPD         string `json:"to_pd"`
+	ToMode       string `json:"to_mode"`
+	RenamePrefix string `json:"rename_prefix,omitempty"`
+	// the last source key copied, in string literal, empty if nothing is copied
+	LastKey string `json:"last_key"`
+	Batches int    `json:"batches"`
+	Rows    int    `json:"rows"`
+}
+
+func loadCopyCheckpoint(filename string) (*copyCheckpoint, error) {
+	cp := &copyCheckpoint{}
+	if err := loadJSONFile(filename, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// keyRenamer replaces the prefix old of the keys with new
+type keyRenamer struct {
+	old []byte
+	new []byte
+}
+
+// parseRenamePrefix parses <old>:<new>, nil means no renaming
+func parseRenamePrefix(s string) (*keyRenamer, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid rename-prefix: %s, should be <old prefix>:<new prefix>", s)
+	}
+	old, err := utils.GetStringLit(s[:i])
+	if err != nil {
+		return nil, err
+	}
+	new, err := utils.GetStringLit(s[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	return &keyRenamer{old: old, new: new}, nil
+}
+
+func (r *keyRenamer) rename(k []byte) ([]byte, error) {
+	if r == nil {
+		return k, nil
+	}
+	if !bytes.HasPrefix(k, r.old) {
+		return nil, fmt.Errorf("key %s doesn't have the prefix to rename: %s", utils.Bytes2StrLit(k), utils.Bytes2StrLit(r.old))
+	}
+	ret := make([]byte, 0, len(r.new)+len(k)-len(r.old))
+	ret = append(ret, r.new...)
+	return append(ret, k[len(r.old):]...), nil
+}
+
+// renameRange returns the range of the renamed keys of [start, end), which
+// should be within the prefix to rename
+func (r *keyRenamer) renameRange(start, end []byte) ([]byte, []byte, error) {
+	if r == nil {
+		return start, end, nil
+	}
+	newStart, err := r.rename(start)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(end, utils.PrefixEnd(r.old)) {
+		return newStart, utils.PrefixEnd(r.new), nil
+	}
+	if !bytes.HasPrefix(end, r.old) {
+		return nil, nil, fmt.Errorf("the end key %s is not within the prefix to rename: %s", utils.Bytes2StrLit(end), utils.Bytes2StrLit(r.old))
+	}
+	newEnd, _ := r.rename(end)
+	return newStart, newEnd, nil
+}
+
+func (r *keyRenamer) renameKVs(kvs client.KVS) (client.KVS, error) {
+	if r == nil {
+		return kvs, nil
+	}
+	ret := make(client.KVS, 0, len(kvs))
+	for _, kv := range kvs {
+		k, err := r.rename(kv.K)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, client.KV{K: k, V: kv.V})
+	}
+	return ret, nil
+}
+
+// copyDryRun counts the keys to copy, and the keys already existing in dst
+func copyDryRun(ctx context.Context, dst client.Client, start, end []byte, renamer *keyRenamer, scanOpt client.ParallelScanOpt) error {
+	var rows, existing int
+	err := client.ParallelScan(ctx, client.GetTiKVClient(), start, end, scanOpt, func(kvs client.KVS) error {
+		kvs, err := renamer.renameKVs(kvs)
+		if err != nil {
+			return err
+		}
+		keys := make([]client.Key, 0, len(kvs))
+		for _, kv := range kvs {
+			keys = append(keys, kv.K)
+		}
+		// the timestamp of the source cluster is meaningless to the destination
+		found, err := dst.BatchGet(utils.ContextWithProp(ctx, client.LatestReadOpt(nil)), keys)
+		if err != nil {
+			return err
+		}
+		rows += len(kvs)
+		existing += len(found)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	utils.Print("Dry run, nothing is written")
+	client.KVS([]client.KV{
+		{K: []byte("Rows"), V: []byte(fmt.Sprintf("%d", rows))},
+		{K: []byte("Existing"), V: []byte(fmt.Sprintf("%d", existing))},
+	}).Print()
+	return nil
+}
+
+func (c CopyCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			// parse args and options from raw args to keep the string literals
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if len(args) < 2 { // args[0] is the command name
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			prefix, err := utils.GetStringLit(args[1])
+			if err != nil {
+				return err
+			}
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			toPD := opt.GetString(tcli.CopyOptToPD, "")
+			if len(toPD) == 0 {
+				return errors.New("the destination PD address is required, use --to-pd=<addr>")
+			}
+			defaultMode := "txn"
+			if client.GetTiKVClient().GetClientMode() == client.RAW_CLIENT {
+				defaultMode = "raw"
+			}
+			toMode := strings.ToLower(opt.GetString(tcli.CopyOptToMode, defaultMode))
+			if toMode != "raw" && toMode != "txn" {
+				return fmt.Errorf("invalid to-mode: %s, should be raw or txn", toMode)
+			}
+			renamePrefix := opt.GetString(tcli.CopyOptRenamePrefix, "")
+			renamer, err := parseRenamePrefix(renamePrefix)
+			if err != nil {
+				return err
+			}
+			start, end, err := getScanRange(prefix, opt)
+			if err != nil {
+				return err
+			}
+			dstStart, dstEnd, err := renamer.renameRange(start, end)
+			if err != nil {
+				return err
+			}
+			scanOpt := client.ParallelScanOpt{
+				Concurrency: opt.GetInt(tcli.ScanOptConcurrency, defaultScanConcurrency),
+				BatchSize:   opt.GetInt(tcli.CopyOptBatchSize, 1000),
+				Ordered:     true,
+				ScanOpt:     opt,
+			}
+
+			dst, err := client.NewClient(utils.SplitList(toPD), toMode, client.GetDefaultSecurity(), client.GetDefaultAPI())
+			if err != nil {
+				return fmt.Errorf("connect to the destination %s: %s", toPD, err)
+			}
+			defer dst.Close()
+
+			ctx, stop := utils.WithInterrupt(context.TODO())
+			defer stop()
+			if opt.GetBool(tcli.CopyOptDryRun, false) {
+				return copyDryRun(ctx, dst, start, end, renamer, scanOpt)
+			}
+
+			checkpointFile := opt.GetString(tcli.CopyOptCheckpoint, defaultCopyCheckpointFile)
+			resume := opt.GetBool(tcli.CopyOptResume, false)
+			cp := &copyCheckpoint{
+				Start:        utils.Bytes2StrLit(start),
+				End:          utils.Bytes2StrLit(end),
+				ToPD:         toPD,
+				ToMode:       toMode,
+				RenamePrefix: renamePrefix,
+			}
+			scanStart := start
+			if resume {
+				saved, err := loadCopyCheckpoint(checkpointFile)
+				if err != nil {
+					return err
+				}
+				if saved.Start != cp.Start || saved.End != cp.End || saved.ToPD != cp.ToPD ||
+					saved.ToMode != cp.ToMode || saved.RenamePrefix != cp.RenamePrefix {
+					return fmt.Errorf("the checkpoint is for the copy of [%s, %s) to %s (%s), not the same copy",
+						saved.Start, saved.End, saved.ToPD, saved.ToMode)
+				}
+				cp = saved
+				if len(cp.LastKey) > 0 {
+					lastKey, err := utils.GetStringLit(cp.LastKey)
+					if err != nil {
+						return err
+					}
+					scanStart = utils.NextKey(lastKey)
+				}
+				utils.PrintProgress(fmt.Sprintf("Resume from checkpoint, rows: %d, last key: %s", cp.Rows, cp.LastKey))
+			} else if _, err := os.Stat(checkpointFile); err == nil {
+				return fmt.Errorf("checkpoint file %s already exists, use --resume to continue the copy, or remove it", checkpointFile)
+			}
+
+			tt := time.Now()
+			batches, rows := 0, 0
+			stats := &loadStats{}
+			maxRetries := opt.GetInt(tcli.CopyOptMaxRetries, 3)
+			err = client.ParallelScan(ctx, client.GetTiKVClient(), scanStart, end, scanOpt, func(kvs client.KVS) error {
+				lastKey := kvs[len(kvs)-1].K
+				kvs, err := renamer.renameKVs(kvs)
+				if err != nil {
+					return err
+				}
+				if err := loadBatch(ctx, dst, kvs, nil, maxRetries, stats); err != nil {
+					return err
+				}
+				batches++
+				rows += len(kvs)
+				cp.Batches++
+				cp.Rows += len(kvs)
+				cp.LastKey = utils.Bytes2StrLit(lastKey)
+				if err := saveJSONFile(checkpointFile, cp); err != nil {
+					return err
+				}
+				utils.PrintProgress(fmt.Sprintf("Copy a batch, batch size: %d, Last key: %s", len(kvs), lastKey))
+				return nil
+			})
+			if err != nil {
+				if batches > 0 || resume {
+					return fmt.Errorf("%s, use --resume to continue the copy", err)
+				}
+				return err
+			}
+			// the copy is done
+			os.Remove(checkpointFile)
+
+			// compare the number of keys in the source and the destination
+			srcOpt := properties.NewProperties()
+			srcOpt.Merge(opt)
+			srcRows, err := countRange(ctx, client.GetTiKVClient(), start, end, srcOpt)
+			if err != nil {
+				return err
+			}
+			dstOpt := client.LatestReadOpt(nil)
+			dstOpt.Set(tcli.ScanOptConcurrency, fmt.Sprintf("%d", scanOpt.Concurrency))
+			dstRows, err := countRange(ctx, dst, dstStart, dstEnd, dstOpt)
+			if err != nil {
+				return err
+			}
+
+			result := []client.KV{
+				{K: []byte("Batches"), V: []byte(fmt.Sprintf("%d", batches))},
+				{K: []byte("Rows"), V: []byte(fmt.Sprintf("%d", rows))},
+			}
+			if resume {
+				result = append(result, client.KV{K: []byte("Total Rows"), V: []byte(fmt.Sprintf("%d", cp.Rows))})
+			}
+			result = append(result,
+				client.KV{K: []byte("Retries"), V: []byte(fmt.Sprintf("%d", stats.retries.Load()))},
+				client.KV{K: []byte("Source Rows"), V: []byte(fmt.Sprintf("%d", srcRows))},
+				client.KV{K: []byte("Destination Rows"), V: []byte(fmt.Sprintf("%d", dstRows))},
+				client.KV{K: []byte("Elapsed"), V: []byte(time.Since(tt).Round(time.Millisecond).String())},
+			)
+			client.KVS(result).Print()
+			if srcRows != dstRows {
+				utils.Print(fmt.Sprintf("Warning: the source has %d rows, but the destination has %d rows", srcRows, dstRows))
+			}
+			return nil
+		})
+	}
+}
@@ -1,9 +1,9 @@
 package kvcmds
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/c4pt0r/tcli"
 	"github.com/c4pt0r/tcli/client"
@@ -25,9 +25,18 @@ func (c CountCmd) LongHelp() string {
 	s := c.Help()
 	s += `
 Usage:
-	count [key prefix | *]
+	count [key prefix | *] <options>
+Options:
+	--yes, force yes
+	--end=<end key>, count keys in [start key, end key), the first argument is used as start key
+	--as-of=<tso|RFC3339 time>, count keys in the snapshot at the timestamp, txn mode only
+	--concurrency=<n>, number of regions counted in parallel, default: 8
 Alias:
 	cnt
+Examples:
+	count "t_" --yes
+	count "a" --end="b" --yes
+	count * --concurrency=32 --yes
 `
 	return s
 }
@@ -44,28 +53,33 @@ func (c CountCmd) Handler() func(ctx context.Context) {
 			if err != nil {
 				return err
 			}
+			scanOpt := properties.NewProperties()
+			// parse options from raw args to keep the string literals
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, scanOpt); err != nil {
+				return err
+			}
+			_, rangeMode := scanOpt.Get(tcli.ScanOptEnd)
+
 			promptMsg := fmt.Sprintf("Are you going to count all keys with prefix :%s", prefix)
-			if string(prefix) == "*" {
+			if rangeMode {
+				promptMsg = fmt.Sprintf("Are you going to count all keys from :%s to :%s", prefix, scanOpt.GetString(tcli.ScanOptEnd, ""))
+			} else if string(prefix) == "*" {
 				promptMsg = "Are you going to count all keys? (may be very slow when your data is huge)"
 			}
 
-			var yes bool
-			if utils.HasForceYes(ctx) {
-				yes = true
-			} else {
-				yes = utils.AskYesNo(promptMsg, "no") == 1
+			yes, err := utils.Confirm(ctx, promptMsg)
+			if err != nil {
+				return err
 			}
 			if yes {
-				scanOpt := properties.NewProperties()
-				scanOpt.Set(tcli.ScanOptCountOnly, "true")
-				scanOpt.Set(tcli.ScanOptKeyOnly, "true")
-				scanOpt.Set(tcli.ScanOptStrictPrefix, "true")
-				// count all mode
-				if string(prefix) == "*" || bytes.Compare(prefix, []byte("\x00")) == 0 {
-					prefix = []byte("\x00")
-					scanOpt.Set(tcli.ScanOptStrictPrefix, "false")
+				start, end, err := getScanRange(prefix, scanOpt)
+				if err != nil {
+					return err
 				}
-				_, cnt, err := client.GetTiKVClient().Scan(utils.ContextWithProp(context.TODO(), scanOpt), prefix)
+				ctx, stop := utils.WithInterrupt(context.TODO())
+				defer stop()
+				cnt, err := countRange(ctx, client.GetTiKVClient(), start, end, scanOpt)
 				if err != nil {
 					return err
 				}
@@ -75,3 +89,19 @@ func (c CountCmd) Handler() func(ctx context.Context) {
 		})
 	}
 }
+
+// countRange counts the keys in [start, end) of c, the regions are scanned in
+// parallel
+func countRange(ctx context.Context, c client.Client, start, end []byte, scanOpt *properties.Properties) (int64, error) {
+	scanOpt.Set(tcli.ScanOptKeyOnly, "true")
+	var cnt int64
+	err := client.ParallelScan(ctx, c, start, end, client.ParallelScanOpt{
+		Concurrency: scanOpt.GetInt(tcli.ScanOptConcurrency, defaultScanConcurrency),
+		BatchSize:   client.MaxRawKVScanLimit,
+		ScanOpt:     scanOpt,
+	}, func(kvs client.KVS) error {
+		atomic.AddInt64(&cnt, int64(len(kvs)))
+		return nil
+	})
+	return cnt, err
+}
@@ -37,11 +37,9 @@ Alias:
 func (c DeleteAllCmd) Handler() func(ctx context.Context) {
 	return func(ctx context.Context) {
 		utils.OutputWithElapse(func() error {
-			var yes bool
-			if utils.HasForceYes(ctx) {
-				yes = true
-			} else {
-				yes = utils.AskYesNo(fmt.Sprintf("Delete all keys, are you sure?"), "no") == 1
+			yes, err := utils.Confirm(ctx, "Delete all keys, are you sure?")
+			if err != nil {
+				return err
 			}
 			if yes {
 				utils.Print("Your call")
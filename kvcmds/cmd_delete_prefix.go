@@ -30,10 +30,47 @@ Alias:
 Options:
 	--yes, force yes
 	--limit=<limit>, default: 1000
+	--end=<end key>, delete kv pairs in [start key, end key), the first argument is used as start key
+	--batch-size=<size>, keys deleted in one batch, default: 1000
+	--concurrency=<n>, number of regions scanned in parallel, default: 8, the keys are deleted in order
+Examples:
+	delp "t_" --yes
+	delp "a" --end="b" --limit=10000 --yes
 `
 	return s
 }
 
+// deleteRange deletes at most limit keys in [start, end). regions are
+// scanned in parallel, but deleted in key order, so the deleted keys are
+// always the first ones in the range. returns the last deleted key and the
+// number of deleted keys
+func deleteRange(ctx context.Context, start, end []byte, limit int, opt *properties.Properties) (client.Key, int, error) {
+	// the keys to delete are always the latest ones
+	scanOpt := client.LatestReadOpt(nil)
+	scanOpt.Set(tcli.ScanOptKeyOnly, "true")
+
+	var lastKey client.Key
+	total := 0
+	err := client.ParallelScan(ctx, client.GetTiKVClient(), start, end, client.ParallelScanOpt{
+		Concurrency: opt.GetInt(tcli.ScanOptConcurrency, defaultScanConcurrency),
+		BatchSize:   opt.GetInt(tcli.DeleteOptBatchSize, 1000),
+		Ordered:     true,
+		ScanOpt:     scanOpt,
+	}, func(kvs client.KVS) error {
+		if total >= limit {
+			return client.ErrStopScan
+		}
+		kvs = kvs[:min(len(kvs), limit-total)]
+		if err := client.GetTiKVClient().BatchDelete(context.TODO(), kvs); err != nil {
+			return err
+		}
+		total += len(kvs)
+		lastKey = kvs[len(kvs)-1].K
+		return nil
+	})
+	return lastKey, total, err
+}
+
 func (c DeletePrefixCmd) Handler() func(ctx context.Context) {
 	return func(ctx context.Context) {
 		utils.OutputWithElapse(func() error {
@@ -47,25 +84,36 @@ func (c DeletePrefixCmd) Handler() func(ctx context.Context) {
 				return err
 			}
 			opt := properties.NewProperties()
-			if len(ic.Args) > 1 {
-				err := utils.SetOptByString(ic.Args[1:], opt)
-				if err != nil {
-					return err
-				}
+			// parse options from raw args to keep the string literals
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
 			}
 			opt.Set(tcli.DeleteOptWithPrefix, "true")
 			limit := opt.GetInt(tcli.DeleteOptLimit, 1000)
+			end, err := utils.GetStringLitOpt(opt, tcli.ScanOptEnd)
+			if err != nil {
+				return err
+			}
 
-			var yes bool
-			if utils.HasForceYes(ctx) {
-				yes = true
-			} else {
-				yes = utils.AskYesNo("Are you sure to delete kv pairs with prefix: %s", string(k)) == 1
+			rangeMode := end != nil
+			promptMsg := fmt.Sprintf("Are you sure to delete kv pairs with prefix: %s", k)
+			if rangeMode {
+				promptMsg = fmt.Sprintf("Are you sure to delete kv pairs from: %s to: %s", k, end)
+			}
+			yes, err := utils.Confirm(ctx, promptMsg)
+			if err != nil {
+				return err
 			}
 
 			if yes {
 				utils.Print("Your call")
-				lastKey, cnt, err := client.GetTiKVClient().DeletePrefix(ctx, k, limit)
+				if !rangeMode {
+					end = utils.PrefixEnd(k)
+				}
+				ctx, stop := utils.WithInterrupt(context.TODO())
+				defer stop()
+				lastKey, cnt, err := deleteRange(ctx, k, end, limit, opt)
 				if err != nil {
 					return err
 				}
@@ -77,9 +125,6 @@ func (c DeletePrefixCmd) Handler() func(ctx context.Context) {
 			} else {
 				utils.Print("Nothing happened")
 			}
-			if err != nil {
-				return err
-			}
 			return nil
 		})
 	}
@@ -0,0 +1,426 @@
+package kvcmds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+)
+
+var _ tcli.Cmd = DiffCmd{}
+
+type DiffCmd struct{}
+
+func (c DiffCmd) Name() string    { return "diff" }
+func (c DiffCmd) Alias() []string { return []string{"diff"} }
+func (c DiffCmd) Help() string {
+	return "compare the kv pairs of two prefixes, or of a prefix in two clusters"
+}
+
+func (c DiffCmd) LongHelp() string {
+	var buf bytes.Buffer
+	buf.WriteString(c.Help())
+	buf.WriteString(`
+Usage:
+	diff <left prefix> <right prefix> <opts>
+	diff <prefix> --against-pd=<addr> <opts>
+Options:
+	--against-pd=<addr>, PD address of the cluster to compare against, comma separated for multiple addresses, connected with the TLS flags (--ca, --cert, --key) and the API flags (--api-version, --keyspace) of tcli
+	--against-mode=raw|txn, TiKV API mode of the cluster to compare against, default: the mode of the current client
+	--output=table|json|patch, default: table, or json if sys.printfmt is json
+		patch: write a backup file which makes the right side have the left kv pairs, see below
+	--patch-file=<file>, the file to write the patch to, required by --output=patch
+	--limit=<n>, max number of the different keys to print, default: 1000
+	--as-of=<tso|RFC3339 time>, compare the snapshots at the timestamp, txn mode only
+	--concurrency=<n>, number of regions scanned in parallel, default: 8
+Description:
+	the keys are compared without their prefixes, and reported as:
+		left-only: the keys only existing in the left
+		right-only: the keys only existing in the right
+		different: the keys existing in both, with different values
+	the patch file is in the csv backup format, with the left-only and the different keys renamed
+	to the right prefix and their left values, apply it to the right side with:
+		restore <patch file> --overwrite=always
+	the right-only keys are not in the patch file.
+	in txn mode, both prefixes in the current cluster are read at the same timestamp.
+	diff is not supported in an open transaction.
+Example:
+	# compare the keys with prefix "a_" and "b_"
+	diff "a_" "b_"
+
+	# compare the keys with prefix "t_" with another cluster, in json
+	diff "t_" --against-pd=10.0.1.1:2379 --output=json
+
+	# write the patch to make "b_" the same as "a_"
+	diff "a_" "b_" --output=patch --patch-file=patch.csv
+`)
+	return buf.String()
+}
+
+const (
+	diffOutputTable = "table"
+	diffOutputJSON  = "json"
+	diffOutputPatch = "patch"
+
+	diffLeftOnly  = "left-only"
+	diffRightOnly = "right-only"
+	diffDifferent = "different"
+)
+
+// diffEntry is a key reported by diff, the key is the left key unless the
+// key is right-only
+type diffEntry struct {
+	Type       string `json:"type"`
+	Key        string `json:"key"`
+	LeftValue  string `json:"left_value,omitempty"`
+	RightValue string `json:"right_value,omitempty"`
+}
+
+type diffStats struct {
+	leftRows  int
+	rightRows int
+	leftOnly  int
+	rightOnly int
+	different int
+	reported  int
+	patchRows int
+}
+
+// diffResult is the output of diff in json
+type diffResult struct {
+	LeftRows  int         `json:"left_rows"`
+	RightRows int         `json:"right_rows"`
+	LeftOnly  int         `json:"left_only"`
+	RightOnly int         `json:"right_only"`
+	Different int         `json:"different"`
+	Diffs     []diffEntry `json:"diffs"`
+}
+
+// kvStream iterates the kv pairs of a range in key order, the range is
+// scanned by ParallelScan in the background
+type kvStream struct {
+	ch   chan client.KVS
+	errc chan error
+	buf  client.KVS
+	done bool
+	err  error
+}
+
+func newKVStream(ctx context.Context, c client.Client, start, end []byte, opt client.ParallelScanOpt) *kvStream {
+	s := &kvStream{
+		ch:   make(chan client.KVS, 4),
+		errc: make(chan error, 1),
+	}
+	opt.Ordered = true
+	go func() {
+		err := client.ParallelScan(ctx, c, start, end, opt, func(kvs client.KVS) error {
+			select {
+			case s.ch <- kvs:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		s.errc <- err
+		close(s.ch)
+	}()
+	return s
+}
+
+// next returns the next kv pair, ok is false at the end of the range or on
+// error, which is saved in s.err
+func (s *kvStream) next() (client.KV, bool) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return client.KV{}, false
+		}
+		kvs, ok := <-s.ch
+		if !ok {
+			s.done = true
+			s.err = <-s.errc
+			return client.KV{}, false
+		}
+		s.buf = kvs
+	}
+	kv := s.buf[0]
+	s.buf = s.buf[1:]
+	return kv, true
+}
+
+// diffPrefix returns the prefix of the argument, "*" means all keys
+func diffPrefix(arg string) ([]byte, error) {
+	prefix, err := utils.GetStringLit(arg)
+	if err != nil {
+		return nil, err
+	}
+	if string(prefix) == "*" {
+		return nil, nil
+	}
+	return prefix, nil
+}
+
+// diffRange returns the scan range of the keys with prefix
+func diffRange(prefix []byte) ([]byte, []byte) {
+	if len(prefix) == 0 {
+		return []byte("\x00"), nil
+	}
+	return prefix, utils.PrefixEnd(prefix)
+}
+
+func (c DiffCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			// parse args and options from raw args to keep the string literals
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if len(args) < 2 { // args[0] is the command name
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			leftPrefix, err := diffPrefix(args[1])
+			if err != nil {
+				return err
+			}
+			rightPrefix := leftPrefix
+			if len(args) > 2 {
+				if rightPrefix, err = diffPrefix(args[2]); err != nil {
+					return err
+				}
+			}
+			againstPD := opt.GetString(tcli.DiffOptAgainstPD, "")
+			if len(args) < 3 && len(againstPD) == 0 {
+				return errors.New("nothing to compare with, use diff <left prefix> <right prefix> or diff <prefix> --against-pd=<addr>")
+			}
+
+			defaultOutput := diffOutputTable
+			if r, ok := utils.SysVarGet(utils.SysVarPrintFormatKey); ok && string(r) == "json" {
+				defaultOutput = diffOutputJSON
+			}
+			output := opt.GetString(tcli.DiffOptOutput, defaultOutput)
+			patchFile := opt.GetString(tcli.DiffOptPatchFile, "")
+			switch output {
+			case diffOutputTable, diffOutputJSON:
+			case diffOutputPatch:
+				if len(patchFile) == 0 {
+					return errors.New("the patch file is required, use --patch-file=<file>")
+				}
+			default:
+				return fmt.Errorf("invalid output: %s, should be table, json or patch", output)
+			}
+			limit := opt.GetInt(tcli.DiffOptLimit, 1000)
+
+			left, right := client.GetTiKVClient(), client.GetTiKVClient()
+			// both sides are scanned concurrently, which the open transaction
+			// doesn't support
+			if info := left.GetTxnInfo(); info != nil {
+				return fmt.Errorf("transaction %d is still open, commit or rollback it before diff", info.StartTS)
+			}
+			if len(againstPD) > 0 {
+				defaultMode := "txn"
+				if left.GetClientMode() == client.RAW_CLIENT {
+					defaultMode = "raw"
+				}
+				mode := strings.ToLower(opt.GetString(tcli.DiffOptAgainstMode, defaultMode))
+				if mode != "raw" && mode != "txn" {
+					return fmt.Errorf("invalid against-mode: %s, should be raw or txn", mode)
+				}
+				right, err = client.NewClient(utils.SplitList(againstPD), mode, client.GetDefaultSecurity(), client.GetDefaultAPI())
+				if err != nil {
+					return fmt.Errorf("connect to %s: %s", againstPD, err)
+				}
+				defer right.Close()
+			}
+
+			var dw dumpWriter
+			if output == diffOutputPatch {
+				if _, err := os.Stat(patchFile); !os.IsNotExist(err) {
+					return fmt.Errorf("patch file %s already exists", patchFile)
+				}
+				fp, err := os.Create(patchFile)
+				if err != nil {
+					return err
+				}
+				defer fp.Close()
+				if dw, err = newDumpWriter(dumpFormatCSV, fp, false, 0); err != nil {
+					return err
+				}
+			}
+
+			ctx, stop := utils.WithInterrupt(context.TODO())
+			defer stop()
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			// both sides read the same snapshot if they are in the same cluster
+			readOpt, err := client.PinSnapshot(left, opt)
+			if err != nil {
+				return err
+			}
+			scanOpt := client.ParallelScanOpt{
+				Concurrency: opt.GetInt(tcli.ScanOptConcurrency, defaultScanConcurrency),
+				BatchSize:   1000,
+				ScanOpt:     readOpt,
+			}
+			rightOpt := scanOpt
+			if right != left {
+				// the timestamp of the current cluster is meaningless to the other one
+				rightOpt.ScanOpt = client.LatestReadOpt(nil)
+			}
+			leftStart, leftEnd := diffRange(leftPrefix)
+			rightStart, rightEnd := diffRange(rightPrefix)
+			ls := newKVStream(ctx, left, leftStart, leftEnd, scanOpt)
+			rs := newKVStream(ctx, right, rightStart, rightEnd, rightOpt)
+
+			stats := &diffStats{}
+			var entries []diffEntry
+			var patch client.KVS
+			report := func(typ string, l, r *client.KV) error {
+				switch typ {
+				case diffLeftOnly:
+					stats.leftOnly++
+				case diffRightOnly:
+					stats.rightOnly++
+				case diffDifferent:
+					stats.different++
+				}
+				if dw != nil {
+					if l == nil {
+						return nil
+					}
+					k := append(append([]byte{}, rightPrefix...), l.K[len(leftPrefix):]...)
+					patch = append(patch, client.KV{K: k, V: l.V})
+					stats.patchRows++
+					if len(patch) < 1000 {
+						return nil
+					}
+					err := dw.WriteKVs(patch)
+					patch = nil
+					return err
+				}
+				if stats.reported >= limit {
+					return nil
+				}
+				stats.reported++
+				e := diffEntry{Type: typ}
+				if l != nil {
+					e.Key, e.LeftValue = string(l.K), string(l.V)
+				}
+				if r != nil {
+					if l == nil {
+						e.Key = string(r.K)
+					}
+					e.RightValue = string(r.V)
+				}
+				entries = append(entries, e)
+				return nil
+			}
+
+			// merge the two ordered streams by the keys without prefix
+			l, lok := ls.next()
+			r, rok := rs.next()
+			for lok || rok {
+				var cmp int
+				switch {
+				case !lok:
+					cmp = 1
+				case !rok:
+					cmp = -1
+				default:
+					cmp = bytes.Compare(l.K[len(leftPrefix):], r.K[len(rightPrefix):])
+				}
+				switch {
+				case cmp < 0:
+					err = report(diffLeftOnly, &l, nil)
+				case cmp > 0:
+					err = report(diffRightOnly, nil, &r)
+				case !bytes.Equal(l.V, r.V):
+					err = report(diffDifferent, &l, &r)
+				}
+				if err != nil {
+					return err
+				}
+				if cmp <= 0 {
+					stats.leftRows++
+					l, lok = ls.next()
+				}
+				if cmp >= 0 {
+					stats.rightRows++
+					r, rok = rs.next()
+				}
+			}
+			if ls.err != nil {
+				return ls.err
+			}
+			if rs.err != nil {
+				return rs.err
+			}
+
+			switch output {
+			case diffOutputJSON:
+				if entries == nil {
+					entries = []diffEntry{}
+				}
+				out, err := json.MarshalIndent(diffResult{
+					LeftRows:  stats.leftRows,
+					RightRows: stats.rightRows,
+					LeftOnly:  stats.leftOnly,
+					RightOnly: stats.rightOnly,
+					Different: stats.different,
+					Diffs:     entries,
+				}, "", " ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(out))
+			case diffOutputTable:
+				if len(entries) > 0 {
+					data := [][]string{{"Diff", "Key", "Left Value", "Right Value"}}
+					for _, e := range entries {
+						data = append(data, []string{e.Type, e.Key, e.LeftValue, e.RightValue})
+					}
+					utils.PrintTable(data)
+				}
+			case diffOutputPatch:
+				if len(patch) > 0 {
+					if err := dw.WriteKVs(patch); err != nil {
+						return err
+					}
+				}
+				if err := dw.Close(); err != nil {
+					return err
+				}
+			}
+			if output == diffOutputJSON {
+				return nil
+			}
+			if output == diffOutputTable && stats.reported < stats.leftOnly+stats.rightOnly+stats.different {
+				utils.Print(fmt.Sprintf("Only the first %d different keys are printed, use --limit to print more", stats.reported))
+			}
+
+			result := []client.KV{
+				{K: []byte("Left Rows"), V: []byte(fmt.Sprintf("%d", stats.leftRows))},
+				{K: []byte("Right Rows"), V: []byte(fmt.Sprintf("%d", stats.rightRows))},
+				{K: []byte("Left Only"), V: []byte(fmt.Sprintf("%d", stats.leftOnly))},
+				{K: []byte("Right Only"), V: []byte(fmt.Sprintf("%d", stats.rightOnly))},
+				{K: []byte("Different"), V: []byte(fmt.Sprintf("%d", stats.different))},
+			}
+			if output == diffOutputPatch {
+				result = append(result, client.KV{K: []byte("Patch Rows"), V: []byte(fmt.Sprintf("%d", stats.patchRows))})
+			}
+			client.KVS(result).Print()
+			return nil
+		})
+	}
+}
@@ -40,8 +40,11 @@ func (c ExplainCmd) Handler() func(ctx context.Context) {
 				utils.Print(c.LongHelp())
 				return nil
 			}
-			sql := getQueryString(ic)
-			qtxn := query.NewQueryStorage(client.GetTiKVClient())
+			sql, readOpt, err := getQueryString(ic)
+			if err != nil {
+				return err
+			}
+			qtxn := query.NewQueryStorage(client.GetTiKVClient(), readOpt)
 			opt := kvql.NewOptimizer(sql)
 			plan, err := opt.BuildPlan(qtxn)
 			if err != nil {
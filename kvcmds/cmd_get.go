@@ -7,6 +7,7 @@ import (
 	"github.com/c4pt0r/tcli/utils"
 
 	"github.com/c4pt0r/tcli/client"
+	"github.com/magiconair/properties"
 )
 
 type GetCmd struct{}
@@ -20,7 +21,20 @@ func (c GetCmd) Help() string {
 }
 
 func (c GetCmd) LongHelp() string {
-	return c.Help()
+	s := c.Help()
+	s += `
+Usage:
+	get <key> <options>
+Alias:
+	g
+Options:
+	--as-of=<tso|RFC3339 time>, read the value at the timestamp, txn mode only
+Examples:
+	get "a"
+	get "a" --as-of=2006-01-02T15:04:05+08:00
+	get "a" --as-of=425163049473212417
+`
+	return s
 }
 
 func (c GetCmd) Handler() func(ctx context.Context) {
@@ -37,7 +51,13 @@ func (c GetCmd) Handler() func(ctx context.Context) {
 			if err != nil {
 				return err
 			}
-			kv, err := client.GetTiKVClient().Get(context.TODO(), client.Key(k))
+			opt := properties.NewProperties()
+			// parse options from raw args to keep the string literals
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			kv, err := client.GetTiKVClient().Get(utils.ContextWithProp(context.TODO(), opt), client.Key(k))
 			if err != nil {
 				return err
 			}
@@ -3,10 +3,16 @@ package kvcmds
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
+	"github.com/c4pt0r/log"
 	"github.com/c4pt0r/tcli/utils"
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
 
 	"github.com/c4pt0r/tcli/client"
 
@@ -35,6 +41,22 @@ Alias:
 	lcsv
 Options:
 	--batch-size=<size>: int, how many records in one tikv transaction, default: 1000
+	--skip-rows=<rows>: int, how many rows to skip at the beginning of the file, default: 0
+	--ttl=<duration>: the loaded keys expire after the duration, like 30s, 10m or 1h, raw mode only
+	--concurrency=<n>: int, how many workers write the batches concurrently, default: 1
+	--rate-limit=<rows/s>: int, the max rows written per second, 0 means no limit, default: 0
+	--max-retries=<n>: int, how many times to retry a batch on write conflicts and region errors, default: 3
+	--key-template=<template>: build the key from the columns, like user/{id}/{region}, default: {<first column>}
+	--value=json|column:<name>|template:<template>: build the value from the columns, default: json
+		json: a json object of all the columns
+		column:<name>: the value of the column
+		template:<template>: the template filled with the columns, like template:{name},{age}
+Description:
+	by default, each row of the file is a <key>,<value> pair in tcli string literals, like the files written by backup.
+	with --key-template or --value, the file can have any columns, the first row (after the skipped rows) is the header
+	of the column names, the placeholders {<column name>} in the templates are replaced with the column values,
+	use {{ and }} for the literal braces.
+	to load the files written by backup, use restore (alias: load), which recognizes all the backup formats.
 Examples:
 	# load csv file to tikv
 	loadcsv sample.csv 
@@ -44,34 +66,201 @@ Examples:
 
 	# load csv file to tikv with key prefix and skip first row (header)
 	loadcsv sample.csv "prefix_" --batch-size=100 --skip-rows=1
+
+	# load csv file to tikv with 8 workers, at most 10000 rows per second
+	loadcsv sample.csv "prefix_" --concurrency=8 --rate-limit=10000
+
+	# load csv file with header: id,region,name,age, to keys like user/1/us and json values
+	loadcsv users.csv --key-template=user/{id}/{region}
+
+	# load csv file with header, the key is the id and the value is the name
+	loadcsv users.csv "user_" --key-template={id} --value=column:name
+
+	# load csv file to tikv, the keys expire after 1 hour
+	loadcsv sample.csv "cache_" --ttl=1h
 `
 	return s
 }
 
-func (c LoadCsvCmd) processCSV(prop *properties.Properties, rc io.Reader, keyPrefix []byte) error {
+const (
+	loadRetryBaseBackoff = 100 * time.Millisecond
+	loadRetryMaxBackoff  = 5 * time.Second
+	loadProgressInterval = time.Second
+)
+
+// loadStats is updated by the loader workers concurrently
+type loadStats struct {
+	rows    atomic.Int64
+	retries atomic.Int64
+}
+
+// loadBatch writes the batch to c, retries the retryable errors with
+// exponential backoff up to maxRetries times
+func loadBatch(ctx context.Context, c client.Client, kvs client.KVS, limiter *rate.Limiter, maxRetries int, stats *loadStats) error {
+	if limiter != nil {
+		if err := limiter.WaitN(ctx, len(kvs)); err != nil {
+			return err
+		}
+	}
+	backoff := loadRetryBaseBackoff
+	for i := 0; ; i++ {
+		err := c.BatchPut(ctx, kvs)
+		if err == nil {
+			stats.rows.Add(int64(len(kvs)))
+			return nil
+		}
+		if i >= maxRetries || !client.IsRetryableError(err) {
+			return err
+		}
+		log.D("load batch failed, retry after", backoff, "error:", err)
+		stats.retries.Inc()
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > loadRetryMaxBackoff {
+			backoff = loadRetryMaxBackoff
+		}
+	}
+}
+
+// printLoadProgress prints the progress every loadProgressInterval until ctx is done
+func printLoadProgress(ctx context.Context, rc *utils.ProgressReader, stats *loadStats) {
+	ticker := time.NewTicker(loadProgressInterval)
+	defer ticker.Stop()
+	lastRows, lastBytes, lastTime := int64(0), int64(0), time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			rows, bytes := stats.rows.Load(), rc.ReadSize()
+			secs := now.Sub(lastTime).Seconds()
+			utils.PrintProgress(fmt.Sprintf("Progress: %d%% Count: %d Speed: %.0f rows/s, %.2f MB/s",
+				int(rc.GetProgress()*100), rows,
+				float64(rows-lastRows)/secs, float64(bytes-lastBytes)/secs/1024/1024))
+			lastRows, lastBytes, lastTime = rows, bytes, now
+		}
+	}
+}
+
+func (c LoadCsvCmd) processCSV(ctx context.Context, prop *properties.Properties, rc *utils.ProgressReader, keyPrefix []byte) error {
 	r := csv.NewReader(rc)
 	var cnt int
 	var batch []client.KV
 
+	// with key template or value, the columns are mapped to kv pairs by the header
+	keyTmpl := prop.GetString(tcli.LoadFileOptKeyTemplate, "")
+	valueOpt := prop.GetString(tcli.LoadFileOptValue, "")
+	useMapping := len(keyTmpl) > 0 || len(valueOpt) > 0
+	var mapping *csvMapping
+	if useMapping {
+		r.FieldsPerRecord = -1
+	}
+
 	batchSize := prop.GetInt(tcli.LoadFileOptBatchSize, 1000)
 	skips := prop.GetInt(tcli.LoadFileoptSkipRows, 0)
+	concurrency := prop.GetInt(tcli.LoadFileOptConcurrency, 1)
+	rateLimit := prop.GetInt(tcli.LoadFileOptRateLimit, 0)
+	maxRetries := prop.GetInt(tcli.LoadFileOptMaxRetries, 3)
+	if batchSize <= 0 || concurrency <= 0 || rateLimit < 0 || maxRetries < 0 {
+		return errors.New("invalid options: batch-size and concurrency should be positive, rate-limit and max-retries should not be negative")
+	}
+	// the open transaction can't be written concurrently, and its
+	// conflicts can't be resolved by retrying the batch
+	if client.GetTiKVClient().GetTxnInfo() != nil {
+		concurrency, maxRetries = 1, 0
+	}
+	var limiter *rate.Limiter
+	if rateLimit > 0 {
+		// a batch takes batchSize tokens at once
+		limiter = rate.NewLimiter(rate.Limit(rateLimit), batchSize)
+	}
+
+	ctx, cancel := context.WithCancel(utils.ContextWithProp(ctx, prop))
+	defer cancel()
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// the reader parses the batches and the workers write them, at most
+	// concurrency batches are waiting in the channel
+	stats := &loadStats{}
+	batches := make(chan client.KVS, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for kvs := range batches {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := loadBatch(ctx, client.GetTiKVClient(), kvs, limiter, maxRetries, stats); err != nil {
+					setErr(err)
+					return
+				}
+			}
+		}()
+	}
+	go printLoadProgress(ctx, rc, stats)
+
+	send := func(kvs client.KVS) bool {
+		select {
+		case batches <- kvs:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	start := time.Now()
+read:
 	for {
 		rawRec, err := r.Read()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return err
+			setErr(err)
+			break
 		}
 		if skips > 0 {
 			skips--
 			continue
 		}
-		if len(rawRec) != 2 {
-			return fmt.Errorf("invalid csv record: %v, format should be: <key>,<value>", rawRec)
+		var k, v []byte
+		if useMapping {
+			// the first row is the header
+			if mapping == nil {
+				if mapping, err = newCSVMapping(rawRec, keyTmpl, valueOpt); err != nil {
+					setErr(err)
+					break
+				}
+				continue
+			}
+			kv, err := mapping.toKV(rawRec)
+			if err != nil {
+				setErr(err)
+				break
+			}
+			k, v = kv.K, kv.V
+		} else {
+			if len(rawRec) != 2 {
+				setErr(fmt.Errorf("invalid csv record: %v, format should be: <key>,<value>", rawRec))
+				break
+			}
+			k, _ = utils.GetStringLit(rawRec[0])
+			v, _ = utils.GetStringLit(rawRec[1])
 		}
-		k, _ := utils.GetStringLit(rawRec[0])
-		v, _ := utils.GetStringLit(rawRec[1])
 		cnt++
 		var key []byte
 		if len(keyPrefix) > 0 {
@@ -80,33 +269,33 @@ func (c LoadCsvCmd) processCSV(prop *properties.Properties, rc io.Reader, keyPre
 		} else {
 			key = k
 		}
-		// TODO multi-threaded
 		batch = append(batch, client.KV{
 			K: key,
 			V: v,
 		})
 		if len(batch) == batchSize {
-			// do insert
-			err := client.GetTiKVClient().BatchPut(context.TODO(), batch)
-			if err != nil {
-				return err
+			if !send(batch) {
+				break read
 			}
-			// Show progress
-			progress := rc.(*utils.ProgressReader).GetProgress() * 100
-			utils.Print(fmt.Sprintf("Progress: %d%% Count: %d Last Key: %s", int(progress), cnt, k))
-			// clean buffer
 			batch = nil
 		}
 	}
-	// may have last batch
-	if len(batch) > 0 {
-		// do insert
-		err := client.GetTiKVClient().BatchPut(context.TODO(), batch)
-		if err != nil {
-			return err
-		}
+	// may have last batch, firstErr is set by the workers concurrently, the
+	// canceled ctx tells if there is an error
+	if len(batch) > 0 && ctx.Err() == nil {
+		send(batch)
+	}
+	close(batches)
+	wg.Wait()
+	cancel()
+
+	if firstErr != nil {
+		utils.Print(fmt.Sprintf("Failed, affected records: %d", stats.rows.Load()))
+		return firstErr
 	}
-	utils.Print(fmt.Sprintf("Done, affected records: %d", cnt))
+	secs := time.Since(start).Seconds()
+	utils.Print(fmt.Sprintf("Done, affected records: %d, Speed: %.0f rows/s, %.2f MB/s, Retries: %d",
+		stats.rows.Load(), float64(stats.rows.Load())/secs, float64(rc.ReadSize())/secs/1024/1024, stats.retries.Load()))
 	return nil
 }
 
@@ -140,11 +329,9 @@ func (c LoadCsvCmd) Handler() func(ctx context.Context) {
 
 			// set prop
 			prop := properties.NewProperties()
-			if len(ic.Args) > 2 {
-				err = utils.SetOptByString(flags, prop)
-				if err != nil {
-					return nil
-				}
+			err = utils.SetOptByString(flags, prop)
+			if err != nil {
+				return err
 			}
 			// open file for read
 			fp, rdr, err := utils.OpenFileToProgressReader(csvFile)
@@ -153,8 +340,9 @@ func (c LoadCsvCmd) Handler() func(ctx context.Context) {
 			}
 			defer fp.Close()
 			// TODO should validate first
-			// TODO set batch size
-			return c.processCSV(prop, rdr, keyPrefix)
+			ctx, stop := utils.WithInterrupt(context.TODO())
+			defer stop()
+			return c.processCSV(ctx, prop, rdr, keyPrefix)
 		})
 	}
 }
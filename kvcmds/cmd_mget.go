@@ -0,0 +1,127 @@
+package kvcmds
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+)
+
+type MgetCmd struct{}
+
+var _ tcli.Cmd = MgetCmd{}
+
+func (c MgetCmd) Name() string    { return "mget" }
+func (c MgetCmd) Alias() []string { return []string{"bget"} }
+func (c MgetCmd) Help() string {
+	return `get multiple keys in one batch, mget [key1] [key2] ...`
+}
+
+func (c MgetCmd) LongHelp() string {
+	s := c.Help()
+	s += `
+Usage:
+	mget <key1> <key2> ... <options>
+Alias:
+	bget
+Options:
+	--from-file=<filename>, read keys from the file, one key per line, string literals are supported
+	--include-missing, show the keys not found as rows marked as (missing)
+	--as-of=<tso|RFC3339 time>, read the values at the timestamp, txn mode only
+Examples:
+	mget "a" "b" h'0001'
+	mget --from-file=keys.txt --include-missing
+`
+	return s
+}
+
+// missingValue marks the keys not found in the output of mget
+var missingValue = []byte("(missing)")
+
+// readKeysFromFile reads keys line by line, empty lines are skipped
+func readKeysFromFile(filename string) ([]client.Key, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	var keys []client.Key
+	sc := bufio.NewScanner(fp)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if len(line) == 0 {
+			continue
+		}
+		k, err := utils.GetStringLit(line)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, sc.Err()
+}
+
+func (c MgetCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			if len(ic.Args) < 1 {
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			// parse options from raw args to keep the string literals
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+
+			var keys []client.Key
+			for _, s := range args[1:] {
+				k, err := utils.GetStringLit(s)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, k)
+			}
+			if filename := opt.GetString(tcli.MgetOptFromFile, ""); len(filename) > 0 {
+				fileKeys, err := readKeysFromFile(filename)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, fileKeys...)
+			}
+			if len(keys) == 0 {
+				utils.Print("No keys to get")
+				return nil
+			}
+
+			kvs, err := client.GetTiKVClient().BatchGet(utils.ContextWithProp(context.TODO(), opt), keys)
+			if err != nil {
+				return err
+			}
+			if opt.GetBool(tcli.MgetOptIncludeMissing, false) {
+				found := make(map[string][]byte, len(kvs))
+				for _, kv := range kvs {
+					found[string(kv.K)] = kv.V
+				}
+				kvs = kvs[:0]
+				for _, k := range keys {
+					if v, ok := found[string(k)]; ok {
+						kvs = append(kvs, client.KV{K: k, V: v})
+					} else {
+						kvs = append(kvs, client.KV{K: k, V: missingValue})
+					}
+				}
+			}
+			kvs.Print()
+			return nil
+		})
+	}
+}
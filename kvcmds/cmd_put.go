@@ -8,6 +8,7 @@ import (
 	"github.com/c4pt0r/tcli/utils"
 
 	"github.com/c4pt0r/tcli/client"
+	"github.com/magiconair/properties"
 )
 
 type PutCmd struct{}
@@ -21,26 +22,55 @@ func (c PutCmd) Help() string {
 }
 
 func (c PutCmd) LongHelp() string {
-	return c.Help()
+	s := c.Help()
+	s += `
+Usage:
+	put <key> <value> <options>
+Alias:
+	set
+Options:
+	--if-not-exists, put only if the key doesn't exist, reports whether it's put and the current value
+	--ttl=<duration>, the key expires after the duration, like 30s, 10m or 1h, raw mode only
+Examples:
+	put "a" "b"
+	put h'0001' "b" --if-not-exists
+	put "session_1" "b" --ttl=10m
+`
+	return s
 }
 
 func (c PutCmd) Handler() func(ctx context.Context) {
 	return func(ctx context.Context) {
 		utils.OutputWithElapse(func() error {
 			ic := utils.ExtractIshellContext(ctx)
-			if len(ic.Args) < 2 {
+			// parse options from raw args to keep the string literals
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if len(args) < 3 {
 				fmt.Println(c.LongHelp())
 				return nil
 			}
-			k, err := utils.GetStringLit(ic.RawArgs[1])
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			k, err := utils.GetStringLit(args[1])
 			if err != nil {
 				return err
 			}
-			v, err := utils.GetStringLit(ic.RawArgs[2])
+			v, err := utils.GetStringLit(args[2])
 			if err != nil {
 				return err
 			}
-			err = client.GetTiKVClient().Put(context.TODO(), client.KV{K: k, V: v})
+			putCtx := utils.ContextWithProp(context.TODO(), opt)
+			if opt.GetBool(tcli.PutOptIfNotExists, false) {
+				prev, swapped, err := client.GetTiKVClient().CompareAndSwap(putCtx, k, nil, v)
+				if err != nil {
+					return err
+				}
+				printCASResult(k, prev, v, swapped)
+				return nil
+			}
+			err = client.GetTiKVClient().Put(putCtx, client.KV{K: k, V: v})
 			if err != nil {
 				return err
 			}
@@ -8,10 +8,12 @@ import (
 
 	"github.com/abiosoft/ishell"
 	"github.com/c4pt0r/kvql"
+	"github.com/c4pt0r/log"
 	"github.com/c4pt0r/tcli"
 	"github.com/c4pt0r/tcli/client"
 	"github.com/c4pt0r/tcli/query"
 	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
 )
 
 type QueryCmd struct{}
@@ -28,20 +30,34 @@ func (c QueryCmd) LongHelp() string {
 	s := c.Help()
 	s += `
 Usage:
-	query <Query>
+	query <Query> <options>
+Options:
+	--as-of=<tso|RFC3339 time>, query the snapshot at the timestamp, txn mode only
 
 Example:
 	query select * where key ^= 'k' limit 10
+	query select * where key ^= 'k' limit 10 --as-of=2006-01-02T15:04:05+08:00
 `
 	return s
 }
 
-func getQueryString(ic *ishell.Context) string {
+// getQueryString returns the query and the options (like --as-of=<ts>)
+// which are not part of the query
+func getQueryString(ic *ishell.Context) (string, *properties.Properties, error) {
 	ret := []string{}
+	var flags []string
 	for _, arg := range ic.RawArgs[1:] {
+		if strings.HasPrefix(arg, "--"+tcli.ScanOptAsOf+"=") {
+			flags = append(flags, arg)
+			continue
+		}
 		ret = append(ret, arg)
 	}
-	return strings.Join(ret, " ")
+	opt := properties.NewProperties()
+	if err := utils.SetOptByString(flags, opt); err != nil {
+		return "", nil, err
+	}
+	return strings.Join(ret, " "), opt, nil
 }
 
 func convertColumnToString(c kvql.Column) string {
@@ -78,8 +94,11 @@ func (c QueryCmd) Handler() func(ctx context.Context) {
 				utils.Print(c.LongHelp())
 				return nil
 			}
-			sql := getQueryString(ic)
-			qtxn := query.NewQueryStorage(client.GetTiKVClient())
+			sql, readOpt, err := getQueryString(ic)
+			if err != nil {
+				return err
+			}
+			qtxn := query.NewQueryStorage(client.GetTiKVClient(), readOpt)
 			opt := kvql.NewOptimizer(sql)
 			plan, err := opt.BuildPlan(qtxn)
 			if err != nil {
@@ -92,6 +111,11 @@ func (c QueryCmd) Handler() func(ctx context.Context) {
 			}
 			if len(ret) > 1 {
 				utils.PrintTable(ret)
+			}
+			if utils.IsQuiet() {
+				return nil
+			}
+			if len(ret) > 2 {
 				fmt.Fprintf(os.Stderr, "%d Records Found\n", len(ret)-1)
 			} else {
 				fmt.Fprintf(os.Stderr, "%d Record Found\n", len(ret)-1)
@@ -149,7 +173,7 @@ func (c QueryCmd) getRowsBatch(plan kvql.FinalPlan) ([][]string, error) {
 		if len(rows) == 0 {
 			break
 		}
-		fmt.Println("Exec Cache Hit", ectx.Hit)
+		log.D("Exec Cache Hit", ectx.Hit)
 		for _, cols := range rows {
 			fields := make([]string, len(cols))
 			for i := 0; i < len(cols); i++ {
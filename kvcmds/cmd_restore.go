@@ -0,0 +1,290 @@
+package kvcmds
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/c4pt0r/log"
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+)
+
+var _ tcli.Cmd = RestoreCmd{}
+
+type RestoreCmd struct{}
+
+func (c RestoreCmd) Name() string    { return "restore" }
+func (c RestoreCmd) Alias() []string { return []string{"load"} }
+func (c RestoreCmd) Help() string {
+	return "restore kv pairs from a file written by backup"
+}
+
+func (c RestoreCmd) LongHelp() string {
+	var buf bytes.Buffer
+	buf.WriteString(c.Help())
+	buf.WriteString(`
+Usage:
+	restore <backup file> <opts>
+Alias:
+	load
+Options:
+	--batch-size=<size>, default 1000
+	--dry-run, only check the keys against TiKV, nothing is written
+	--verify, check the backup file against <backup file>.checksum written by backup before restoring,
+		and when the restore is done, check the checksum of the backup range in TiKV against it
+	--overwrite=never|always|if-different, what to do with the keys already existing, default: never
+		never: keep the existing keys
+		always: write all the keys
+		if-different: write the keys whose values are different
+Description:
+	all the formats and compressions of backup are recognized from the file header.
+	the binary backup files are checked with their checksums before anything is written.
+	the keys are reported as:
+		inserted: the keys not existing
+		skipped: the keys existing with the same values
+		conflicted: the keys existing with different values
+Example:
+	restore backup.csv --dry-run
+	restore backup.csv --overwrite=if-different --batch-size=5000
+	restore backup.csv --overwrite=always --verify
+	load backup.bin.zst
+`)
+	return buf.String()
+}
+
+const (
+	restoreOverwriteNever       = "never"
+	restoreOverwriteAlways      = "always"
+	restoreOverwriteIfDifferent = "if-different"
+)
+
+type restoreStats struct {
+	rows       int
+	inserted   int
+	skipped    int
+	conflicted int
+	written    int
+}
+
+// restoreBatch writes the batch according to the overwrite policy
+func restoreBatch(ctx context.Context, kvs client.KVS, overwrite string, dryRun bool, stats *restoreStats) error {
+	keys := make([]client.Key, 0, len(kvs))
+	for _, kv := range kvs {
+		keys = append(keys, kv.K)
+	}
+	// compare with the latest values, not the snapshot of sys.read_ts
+	existing, err := client.GetTiKVClient().BatchGet(utils.ContextWithProp(ctx, client.LatestReadOpt(nil)), keys)
+	if err != nil {
+		return err
+	}
+	values := make(map[string][]byte, len(existing))
+	for _, kv := range existing {
+		values[string(kv.K)] = kv.V
+	}
+
+	var toWrite client.KVS
+	for _, kv := range kvs {
+		v, ok := values[string(kv.K)]
+		switch {
+		case !ok:
+			stats.inserted++
+			toWrite = append(toWrite, kv)
+		case bytes.Equal(v, kv.V):
+			stats.skipped++
+			if overwrite == restoreOverwriteAlways {
+				toWrite = append(toWrite, kv)
+			}
+		default:
+			stats.conflicted++
+			if overwrite != restoreOverwriteNever {
+				toWrite = append(toWrite, kv)
+			}
+		}
+	}
+	stats.rows += len(kvs)
+	if dryRun || len(toWrite) == 0 {
+		return nil
+	}
+	if err := client.GetTiKVClient().BatchPut(ctx, toWrite); err != nil {
+		return err
+	}
+	stats.written += len(toWrite)
+	return nil
+}
+
+func (c RestoreCmd) restore(ctx context.Context, prop *properties.Properties, rdr *utils.ProgressReader) (*restoreStats, error) {
+	overwrite := prop.GetString(tcli.RestoreOptOverwrite, restoreOverwriteNever)
+	switch overwrite {
+	case restoreOverwriteNever, restoreOverwriteAlways, restoreOverwriteIfDifferent:
+	default:
+		return nil, fmt.Errorf("invalid overwrite option: %s, should be never, always or if-different", overwrite)
+	}
+	dryRun := prop.GetBool(tcli.RestoreOptDryRun, false)
+	batchSize := prop.GetInt(tcli.RestoreOptBatchSize, 1000)
+
+	dr, format, err := openDumpReader(rdr)
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+	log.D("restore from backup in format:", format)
+
+	stats := &restoreStats{}
+	var batch client.KVS
+	for {
+		kv, err := dr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		batch = append(batch, kv)
+		if len(batch) == batchSize {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+			if err := restoreBatch(ctx, batch, overwrite, dryRun, stats); err != nil {
+				return stats, err
+			}
+			utils.PrintProgress(fmt.Sprintf("Progress: %d%% Rows: %d Last Key: %s", int(rdr.GetProgress()*100), stats.rows, batch[len(batch)-1].K))
+			batch = nil
+		}
+	}
+	// may have last batch
+	if len(batch) > 0 {
+		if err := restoreBatch(ctx, batch, overwrite, dryRun, stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// checkBackupFile reads through the backup file before it's restored, so a
+// corrupted file is found before anything is written. The binary format is
+// checked with its trailer, and all the formats are checked against expected
+// if it's not nil.
+func checkBackupFile(ctx context.Context, backupFile string, expected *backupChecksum) error {
+	fp, rdr, err := utils.OpenFileToProgressReader(backupFile)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	dr, format, err := openDumpReader(rdr)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+	if format != dumpFormatBinary && expected == nil {
+		return nil
+	}
+
+	var sum kvChecksum
+	for {
+		kv, err := dr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		sum.update(client.KVS{kv})
+		if sum.Count%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			utils.PrintProgress(fmt.Sprintf("Checking the backup file: %d%% Rows: %d", int(rdr.GetProgress()*100), sum.Count))
+		}
+	}
+	if expected != nil && !sum.equal(&expected.kvChecksum) {
+		return fmt.Errorf("verify failed, the backup file is corrupted, checksum: %s, count: %d, bytes: %d, expected checksum: %s, count: %d, bytes: %d",
+			sum.String(), sum.Count, sum.Bytes, expected.String(), expected.Count, expected.Bytes)
+	}
+	return nil
+}
+
+func (c RestoreCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if len(args) < 2 { // args[0] is the command name
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			backupFile := args[1]
+			prop := properties.NewProperties()
+			if err := utils.SetOptByString(flags, prop); err != nil {
+				return err
+			}
+			if _, err := os.Stat(backupCheckpointFile(backupFile)); err == nil {
+				utils.Print("Warning: the checkpoint file exists, the backup may be incomplete")
+			}
+
+			verify := prop.GetBool(tcli.RestoreOptVerify, false)
+			var expected *backupChecksum
+			if verify {
+				expected = &backupChecksum{}
+				if err := loadJSONFile(backupChecksumFile(backupFile), expected); err != nil {
+					return fmt.Errorf("the checksum file written by backup is required by --verify: %s", err)
+				}
+			}
+
+			ctx, stop := utils.WithInterrupt(context.TODO())
+			defer stop()
+			if err := checkBackupFile(ctx, backupFile, expected); err != nil {
+				return err
+			}
+
+			fp, rdr, err := utils.OpenFileToProgressReader(backupFile)
+			if err != nil {
+				return err
+			}
+			defer fp.Close()
+			stats, err := c.restore(ctx, prop, rdr)
+			if err != nil {
+				return err
+			}
+			result := []client.KV{
+				{K: []byte("Rows"), V: []byte(fmt.Sprintf("%d", stats.rows))},
+				{K: []byte("Inserted"), V: []byte(fmt.Sprintf("%d", stats.inserted))},
+				{K: []byte("Skipped"), V: []byte(fmt.Sprintf("%d", stats.skipped))},
+				{K: []byte("Conflicted"), V: []byte(fmt.Sprintf("%d", stats.conflicted))},
+				{K: []byte("Written"), V: []byte(fmt.Sprintf("%d", stats.written))},
+			}
+			if prop.GetBool(tcli.RestoreOptDryRun, false) {
+				utils.Print("Dry run, nothing is written")
+			}
+			if !verify {
+				client.KVS(result).Print()
+				return nil
+			}
+
+			start, err := utils.GetStringLit(expected.Start)
+			if err != nil {
+				return err
+			}
+			end, err := utils.GetStringLit(expected.End)
+			if err != nil {
+				return err
+			}
+			actual, err := checksumRange(ctx, client.GetTiKVClient(), start, end, client.LatestReadOpt(nil))
+			if err != nil {
+				return err
+			}
+			result = append(result, expected.kvs("Backup ")...)
+			result = append(result, actual.kvs("TiKV ")...)
+			client.KVS(result).Print()
+			if !actual.equal(&expected.kvChecksum) {
+				return fmt.Errorf("verify failed, the kv pairs in [%s, %s) are different from the backup", expected.Start, expected.End)
+			}
+			utils.Print("Verified, the kv pairs are the same as the backup")
+			return nil
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package kvcmds
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckBackupFile(t *testing.T) {
+	kvs := testDumpKVs()
+	var expected backupChecksum
+	expected.update(kvs)
+	var other backupChecksum
+	other.update(kvs[1:])
+
+	binaryData := writeDump(t, dumpFormatBinary, "", kvs, 10)
+	cases := []struct {
+		name     string
+		data     []byte
+		expected *backupChecksum
+		wantErr  string
+	}{
+		{"binary", binaryData, nil, ""},
+		{"binary with checksum", binaryData, &expected, ""},
+		{"corrupted binary", bytes.Replace(binaryData, []byte("k042"), []byte("k024"), 1), nil, "checksum mismatch"},
+		{"truncated binary", binaryData[:len(binaryData)/2], nil, "truncated"},
+		{"csv", writeDump(t, dumpFormatCSV, "", kvs[1:], 10), nil, ""},
+		{"csv with checksum", writeDump(t, dumpFormatCSV, dumpCompressGzip, kvs, 10), &expected, ""},
+		{"csv with other checksum", writeDump(t, dumpFormatCSV, "", kvs, 10), &other, "the backup file is corrupted"},
+		{"empty jsonl", nil, &backupChecksum{}, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "backup")
+			if err := os.WriteFile(path, tc.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := checkBackupFile(context.TODO(), path, tc.expected)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package kvcmds
 
 import (
+	"bytes"
 	"context"
 	"strconv"
 
@@ -30,6 +31,9 @@ Options:
 	--key-only=<true|false>, default false
 	--strict-prefix=<true|false>, default false
 	--count-only=<true|false>, default false
+	--end=<end key>, scan keys in [start key, end key), default no end key
+	--reverse=<true|false>, scan keys in [start key, end key) from the end, default false
+	--as-of=<tso|RFC3339 time|latest>, read the snapshot at the timestamp, txn mode only, latest overrides sys.read_ts
 Examples:
 	# scan from "a", max 10 keys
 	scan "a" --limit=10
@@ -43,6 +47,20 @@ Examples:
 	# scan from "a", count the number of keys, max 10 keys
 	scan "a" --limit=10 --count-only
 
+	# scan keys in ["a", "b")
+	scan "a" --end="b"
+	scan "a" --end=h'62'
+
+	# scan the last 10 keys in ["a", "b"), then the 10 keys before "a9"
+	scan "a" --end="b" --limit=10 --reverse
+	scan "a" --end="a9" --limit=10 --reverse
+
+	# scan the last 10 keys with prefix "a"
+	scanp "a" --limit=10 --reverse
+
+	# scan from "a", read the snapshot at the time
+	scan "a" --as-of=2006-01-02T15:04:05+08:00
+
 	scan "a" --limit=10 --strict-prefix --key-only=true
 	scan $head --limit=10 --key-only=true
 `
@@ -64,11 +82,10 @@ func (c ScanCmd) Handler() func(ctx context.Context) {
 				return err
 			}
 			scanOpt := properties.NewProperties()
-			if len(ic.Args) > 1 {
-				err := utils.SetOptByString(ic.Args[1:], scanOpt)
-				if err != nil {
-					return err
-				}
+			// parse options from raw args to keep the string literals
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, scanOpt); err != nil {
+				return err
 			}
 			kvs, _, err := client.GetTiKVClient().Scan(utils.ContextWithProp(context.TODO(), scanOpt), startKey)
 			if err != nil {
@@ -80,6 +97,23 @@ func (c ScanCmd) Handler() func(ctx context.Context) {
 	}
 }
 
+// defaultScanConcurrency is the default number of workers of the parallel
+// scans in count, backup and delp
+const defaultScanConcurrency = 8
+
+// getScanRange returns the key range [start, end) of count, backup and delp.
+// keys with prefix are scanned, unless the end key is set in opt, in which
+// case prefix is used as the start key. "*" means all keys.
+func getScanRange(prefix []byte, opt *properties.Properties) ([]byte, []byte, error) {
+	if end, err := utils.GetStringLitOpt(opt, tcli.ScanOptEnd); err != nil || end != nil {
+		return prefix, end, err
+	}
+	if string(prefix) == "*" || bytes.Equal(prefix, []byte("\x00")) {
+		return []byte("\x00"), nil, nil
+	}
+	return prefix, utils.PrefixEnd(prefix), nil
+}
+
 type ScanPrefixCmd struct{}
 
 var _ tcli.Cmd = ScanPrefixCmd{}
@@ -109,11 +143,10 @@ func (c ScanPrefixCmd) Handler() func(ctx context.Context) {
 				return err
 			}
 			scanOpt := properties.NewProperties()
-			if len(ic.Args) > 1 {
-				err := utils.SetOptByString(ic.Args[1:], scanOpt)
-				if err != nil {
-					return err
-				}
+			// parse options from raw args to keep the string literals
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, scanOpt); err != nil {
+				return err
 			}
 			scanOpt.Set(tcli.ScanOptStrictPrefix, "true")
 			kvs, _, err := client.GetTiKVClient().Scan(utils.ContextWithProp(context.TODO(), scanOpt), startKey)
@@ -154,15 +187,60 @@ func (c HeadCmd) Handler() func(ctx context.Context) {
 				return err
 			}
 			scanOpt := properties.NewProperties()
-			if len(ic.Args) > 1 {
-				err := utils.SetOptByString(ic.Args[1:], scanOpt)
-				if err != nil {
-					return err
-				}
+			// parse options from raw args to keep the string literals
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, scanOpt); err != nil {
+				return err
+			}
+			// set limit
+			scanOpt.Set(tcli.ScanOptLimit, ic.Args[0])
+			scanOpt.Set(tcli.ScanOptStrictPrefix, "false")
+			kvs, _, err := client.GetTiKVClient().Scan(utils.ContextWithProp(context.TODO(), scanOpt), []byte("\x00"))
+			if err != nil {
+				return err
+			}
+			kvs.Print()
+			return nil
+		})
+	}
+}
+
+type TailCmd struct{}
+
+var _ tcli.Cmd = TailCmd{}
+
+func (c TailCmd) Name() string    { return "tail" }
+func (c TailCmd) Alias() []string { return []string{"tail"} }
+func (c TailCmd) Help() string {
+	return `scan the last keys in reverse order, equals to "scan $head --reverse limit=N", usage: tail <limit>`
+}
+
+func (c TailCmd) LongHelp() string {
+	return c.Help()
+}
+
+func (c TailCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			if len(ic.Args) < 1 {
+				utils.Print(c.Help())
+				return nil
+			}
+			_, err := strconv.Atoi(ic.Args[0])
+			if err != nil {
+				return err
+			}
+			scanOpt := properties.NewProperties()
+			// parse options from raw args to keep the string literals
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, scanOpt); err != nil {
+				return err
 			}
 			// set limit
 			scanOpt.Set(tcli.ScanOptLimit, ic.Args[0])
 			scanOpt.Set(tcli.ScanOptStrictPrefix, "false")
+			scanOpt.Set(tcli.ScanOptReverse, "true")
 			kvs, _, err := client.GetTiKVClient().Scan(utils.ContextWithProp(context.TODO(), scanOpt), []byte("\x00"))
 			if err != nil {
 				return err
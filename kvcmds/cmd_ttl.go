@@ -0,0 +1,57 @@
+package kvcmds
+
+import (
+	"context"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+)
+
+type TTLCmd struct{}
+
+var _ tcli.Cmd = TTLCmd{}
+
+func (c TTLCmd) Name() string    { return "ttl" }
+func (c TTLCmd) Alias() []string { return []string{"ttl"} }
+func (c TTLCmd) Help() string {
+	return `show the remaining ttl of the key, raw mode only`
+}
+
+func (c TTLCmd) LongHelp() string {
+	s := c.Help()
+	s += `
+Usage:
+	ttl <key>
+Examples:
+	put "session_1" "b" --ttl=10m
+	ttl "session_1"
+`
+	return s
+}
+
+func (c TTLCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			if len(ic.Args) < 1 {
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			k, err := utils.GetStringLit(ic.RawArgs[1])
+			if err != nil {
+				return err
+			}
+			ttl, err := client.GetTiKVClient().GetTTL(context.TODO(), k)
+			if err != nil {
+				return err
+			}
+			v := "never expires"
+			if ttl > 0 {
+				v = ttl.String()
+			}
+			client.KVS([]client.KV{{K: k, V: []byte(v)}}).Print()
+			return nil
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package kvcmds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+)
+
+type BeginCmd struct{}
+
+var _ tcli.Cmd = BeginCmd{}
+
+func (c BeginCmd) Name() string    { return "begin" }
+func (c BeginCmd) Alias() []string { return []string{"begin"} }
+func (c BeginCmd) Help() string {
+	return `begin a transaction, txn mode only`
+}
+
+func (c BeginCmd) LongHelp() string {
+	s := c.Help()
+	s += `
+Usage:
+	begin <options>
+Options:
+	--pessimistic, lock the keys when they are written, instead of checking conflicts at commit
+Description:
+	get, put, del, scan and the other kv commands run in the open transaction
+	until commit or rollback, reads see the uncommitted writes.
+Examples:
+	begin
+	put a b
+	get a
+	commit
+
+	begin --pessimistic
+	del a
+	rollback
+`
+	return s
+}
+
+func (c BeginCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			opt := properties.NewProperties()
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			pessimistic := opt.GetBool(tcli.TxnOptPessimistic, false)
+			if err := client.GetTiKVClient().Begin(context.TODO(), pessimistic); err != nil {
+				return err
+			}
+			info := client.GetTiKVClient().GetTxnInfo()
+			utils.Print(fmt.Sprintf("Transaction started, start ts: %d", info.StartTS))
+			return nil
+		})
+	}
+}
+
+type CommitCmd struct{}
+
+var _ tcli.Cmd = CommitCmd{}
+
+func (c CommitCmd) Name() string    { return "commit" }
+func (c CommitCmd) Alias() []string { return []string{"commit"} }
+func (c CommitCmd) Help() string {
+	return `commit the open transaction`
+}
+
+func (c CommitCmd) LongHelp() string {
+	return c.Help()
+}
+
+func (c CommitCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			return client.GetTiKVClient().Commit(context.TODO())
+		})
+	}
+}
+
+type RollbackCmd struct{}
+
+var _ tcli.Cmd = RollbackCmd{}
+
+func (c RollbackCmd) Name() string    { return "rollback" }
+func (c RollbackCmd) Alias() []string { return []string{"rollback"} }
+func (c RollbackCmd) Help() string {
+	return `rollback the open transaction`
+}
+
+func (c RollbackCmd) LongHelp() string {
+	return c.Help()
+}
+
+func (c RollbackCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			return client.GetTiKVClient().Rollback(context.TODO())
+		})
+	}
+}
@@ -196,7 +196,18 @@ func (c SysVarCmd) Help() string {
 }
 
 func (c SysVarCmd) LongHelp() string {
-	return c.Help()
+	s := c.Help()
+	s += `
+System variables:
+	sys.printfmt, output format, accepted values: [table | json | raw]
+	sys.quiet, don't print the Success/Elapse banners, accepted values: [true | false]
+	sys.read_ts, TSO or RFC3339 time, reads in txn mode see the snapshot at this timestamp, empty means the latest version
+Examples:
+	sysvar sys.printfmt="json"
+	sysvar sys.read_ts="2006-01-02T15:04:05+08:00"
+	sysvar sys.read_ts=""
+`
+	return s
 }
 
 func (c SysVarCmd) Handler() func(ctx context.Context) {
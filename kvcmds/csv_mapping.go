@@ -0,0 +1,174 @@
+package kvcmds
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/c4pt0r/tcli/client"
+)
+
+// the value modes of loadcsv --value
+const (
+	csvValueJSON     = "json"
+	csvValueColumn   = "column:"
+	csvValueTemplate = "template:"
+)
+
+// csvTemplate is a template like "user/{id}/{region}", the placeholders
+// are replaced with the columns of a record, "{{" and "}}" are the escaped
+// braces.
+type csvTemplate struct {
+	parts []csvTemplatePart
+}
+
+// csvTemplatePart is either a literal or a column index
+type csvTemplatePart struct {
+	lit string
+	col int
+}
+
+func parseCSVTemplate(tmpl string, columns map[string]int) (*csvTemplate, error) {
+	t := &csvTemplate{}
+	var lit strings.Builder
+	flushLit := func() {
+		if lit.Len() > 0 {
+			t.parts = append(t.parts, csvTemplatePart{lit: lit.String(), col: -1})
+			lit.Reset()
+		}
+	}
+	for i := 0; i < len(tmpl); i++ {
+		switch c := tmpl[i]; {
+		case c == '{' && strings.HasPrefix(tmpl[i:], "{{"):
+			lit.WriteByte('{')
+			i++
+		case c == '}' && strings.HasPrefix(tmpl[i:], "}}"):
+			lit.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(tmpl[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid template: %s, unclosed '{'", tmpl)
+			}
+			name := tmpl[i+1 : i+end]
+			col, ok := columns[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid template: %s, no column named: %s", tmpl, name)
+			}
+			flushLit()
+			t.parts = append(t.parts, csvTemplatePart{col: col})
+			i += end
+		case c == '}':
+			return nil, fmt.Errorf("invalid template: %s, unexpected '}', use '}}' for a literal '}'", tmpl)
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	flushLit()
+	return t, nil
+}
+
+func (t *csvTemplate) render(rec []string) []byte {
+	var buf bytes.Buffer
+	for _, p := range t.parts {
+		if p.col < 0 {
+			buf.WriteString(p.lit)
+		} else {
+			buf.WriteString(rec[p.col])
+		}
+	}
+	return buf.Bytes()
+}
+
+// csvMapping maps a record of a multi-column csv file with header to a kv pair
+type csvMapping struct {
+	header []string
+	key    *csvTemplate
+	value  func(rec []string) ([]byte, error)
+}
+
+// newCSVMapping builds the mapping from the header, the key template defaults
+// to the first column and the value defaults to json
+func newCSVMapping(header []string, keyTmpl, value string) (*csvMapping, error) {
+	if len(header) == 0 {
+		return nil, errors.New("invalid csv header: no columns")
+	}
+	// excel likes to put a BOM at the beginning of the file
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("invalid csv header: duplicated column: %s", name)
+		}
+		header[i] = name
+		columns[name] = i
+	}
+
+	m := &csvMapping{header: header}
+	if len(keyTmpl) == 0 {
+		keyTmpl = "{" + header[0] + "}"
+	}
+	var err error
+	if m.key, err = parseCSVTemplate(keyTmpl, columns); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(value) == 0 || value == csvValueJSON:
+		m.value = m.jsonValue
+	case strings.HasPrefix(value, csvValueColumn):
+		name := strings.TrimPrefix(value, csvValueColumn)
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid value: %s, no column named: %s", value, name)
+		}
+		m.value = func(rec []string) ([]byte, error) { return []byte(rec[col]), nil }
+	case strings.HasPrefix(value, csvValueTemplate):
+		t, err := parseCSVTemplate(strings.TrimPrefix(value, csvValueTemplate), columns)
+		if err != nil {
+			return nil, err
+		}
+		m.value = func(rec []string) ([]byte, error) { return t.render(rec), nil }
+	default:
+		return nil, fmt.Errorf("invalid value: %s, should be json, column:<name> or template:<template>", value)
+	}
+	return m, nil
+}
+
+// jsonValue encodes the record as a json object, in the order of the header
+func (m *csvMapping) jsonValue(rec []string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range m.header {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(rec[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m *csvMapping) toKV(rec []string) (client.KV, error) {
+	if len(rec) != len(m.header) {
+		return client.KV{}, fmt.Errorf("invalid csv record: %v, expected %d columns as the header", rec, len(m.header))
+	}
+	v, err := m.value(rec)
+	if err != nil {
+		return client.KV{}, err
+	}
+	return client.KV{K: m.key.render(rec), V: v}, nil
+}
@@ -0,0 +1,108 @@
+package kvcmds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSVTemplate(t *testing.T) {
+	columns := map[string]int{"id": 0, "region": 1, "name": 2}
+	rec := []string{"42", "eu", "alice"}
+
+	cases := []struct {
+		tmpl    string
+		want    string
+		wantErr string
+	}{
+		{"{id}", "42", ""},
+		{"user/{id}/{region}", "user/42/eu", ""},
+		{"{region}{id}", "eu42", ""},
+		{"no placeholders", "no placeholders", ""},
+		{"", "", ""},
+		{"{{id}}", "{id}", ""},
+		{"{{{id}}}", "{42}", ""},
+		{"{id", "", "unclosed '{'"},
+		{"id}", "", "unexpected '}'"},
+		{"{age}", "", "no column named: age"},
+		{"{}", "", "no column named: "},
+	}
+	for _, tc := range cases {
+		tmpl, err := parseCSVTemplate(tc.tmpl, columns)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("parseCSVTemplate(%q) returns error %v, want %q", tc.tmpl, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCSVTemplate(%q): %v", tc.tmpl, err)
+			continue
+		}
+		if got := string(tmpl.render(rec)); got != tc.want {
+			t.Errorf("render %q = %q, want %q", tc.tmpl, got, tc.want)
+		}
+	}
+}
+
+func TestCSVMapping(t *testing.T) {
+	header := []string{"\ufeffid", " region ", "name"}
+	rec := []string{"42", "eu", "al\"ice"}
+
+	cases := []struct {
+		name      string
+		key       string
+		value     string
+		wantKey   string
+		wantValue string
+		wantErr   string
+	}{
+		{"default", "", "", "42", `{"id":"42","region":"eu","name":"al\"ice"}`, ""},
+		{"json", "user/{id}", "json", "user/42", `{"id":"42","region":"eu","name":"al\"ice"}`, ""},
+		{"column", "{region}/{id}", "column:name", "eu/42", `al"ice`, ""},
+		{"template", "{id}", "template:{name}@{region}", "42", `al"ice@eu`, ""},
+		{"unknown column in key", "{age}", "", "", "", "no column named: age"},
+		{"unknown value column", "", "column:age", "", "", "no column named: age"},
+		{"invalid value template", "", "template:{name", "", "", "unclosed '{'"},
+		{"invalid value mode", "", "xml", "", "", "invalid value: xml"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := newCSVMapping(append([]string{}, header...), tc.key, tc.value)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			kv, err := m.toKV(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(kv.K) != tc.wantKey || string(kv.V) != tc.wantValue {
+				t.Fatalf("got %q => %q, want %q => %q", kv.K, kv.V, tc.wantKey, tc.wantValue)
+			}
+		})
+	}
+}
+
+func TestCSVMappingInvalid(t *testing.T) {
+	if _, err := newCSVMapping(nil, "", ""); err == nil {
+		t.Fatal("empty header should fail")
+	}
+	if _, err := newCSVMapping([]string{"id", " id"}, "", ""); err == nil || !strings.Contains(err.Error(), "duplicated column: id") {
+		t.Fatalf("got error %v, want duplicated column", err)
+	}
+
+	m, err := newCSVMapping([]string{"id", "name"}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range [][]string{{"1"}, {"1", "a", "b"}} {
+		if _, err := m.toKV(rec); err == nil {
+			t.Fatalf("record %q should fail", rec)
+		}
+	}
+}
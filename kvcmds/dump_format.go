@@ -0,0 +1,398 @@
+package kvcmds
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/klauspost/compress/zstd"
+)
+
+// formats of the files written by backup
+const (
+	dumpFormatCSV    = "csv"
+	dumpFormatJSONL  = "jsonl"
+	dumpFormatBinary = "binary"
+
+	dumpCompressGzip = "gzip"
+	dumpCompressZstd = "zstd"
+)
+
+// The binary format is:
+//
+//	header:  "TCLIDUMP" version(1 byte)
+//	frames:  1(1 byte) uvarint(len(key)) key uvarint(len(value)) value
+//	trailer: 0(1 byte) crc64-ecma(8 bytes, big endian)
+//
+// the checksum covers all the bytes after the header, till the 0 of the trailer.
+var (
+	binaryDumpMagic   = []byte("TCLIDUMP")
+	binaryDumpVersion = byte(1)
+	binaryDumpTable   = crc64.MakeTable(crc64.ECMA)
+)
+
+const (
+	binaryFrameEnd = byte(0)
+	binaryFrameKV  = byte(1)
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// dumpWriter writes kv pairs in one of the dump formats
+type dumpWriter interface {
+	WriteKVs(kvs client.KVS) error
+	// Flush writes the buffered data to the underlying writer
+	Flush() error
+	// Close writes the trailer if there is and flushes, the underlying
+	// writer is not closed
+	Close() error
+}
+
+// checkDumpFormat validates the format and the compression of backup
+func checkDumpFormat(format, compress string) error {
+	switch format {
+	case dumpFormatCSV, dumpFormatJSONL, dumpFormatBinary:
+	default:
+		return fmt.Errorf("invalid format: %s, should be csv, jsonl or binary", format)
+	}
+	switch compress {
+	case "", dumpCompressGzip, dumpCompressZstd:
+	default:
+		return fmt.Errorf("invalid compress: %s, should be gzip or zstd", compress)
+	}
+	return nil
+}
+
+// newDumpWriter returns the writer of format, the header is written unless
+// the backup is resumed, checksum is the checksum of the resumed binary dump
+func newDumpWriter(format string, w io.Writer, resume bool, checksum uint64) (dumpWriter, error) {
+	switch format {
+	case dumpFormatCSV:
+		dw := &csvDumpWriter{w: csv.NewWriter(w)}
+		if !resume {
+			dw.w.Write([]string{"Key", "Value"})
+		}
+		return dw, nil
+	case dumpFormatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlDumpWriter{bw: bw, enc: json.NewEncoder(bw)}, nil
+	case dumpFormatBinary:
+		dw := &binaryDumpWriter{bw: bufio.NewWriter(w), checksum: checksum}
+		if !resume {
+			dw.bw.Write(binaryDumpMagic)
+			dw.bw.WriteByte(binaryDumpVersion)
+		}
+		return dw, nil
+	default:
+		return nil, fmt.Errorf("invalid format: %s, should be csv, jsonl or binary", format)
+	}
+}
+
+// newCompressWriter wraps w with the compressor, nil means no compression
+func newCompressWriter(compress string, w io.Writer) (io.WriteCloser, error) {
+	switch compress {
+	case "":
+		return nil, nil
+	case dumpCompressGzip:
+		return gzip.NewWriter(w), nil
+	case dumpCompressZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("invalid compress: %s, should be gzip or zstd", compress)
+	}
+}
+
+type csvDumpWriter struct {
+	w *csv.Writer
+}
+
+func (dw *csvDumpWriter) WriteKVs(kvs client.KVS) error {
+	return writeKvsToCsvFile(dw.w, kvs)
+}
+
+func (dw *csvDumpWriter) Flush() error {
+	dw.w.Flush()
+	return dw.w.Error()
+}
+
+func (dw *csvDumpWriter) Close() error {
+	return dw.Flush()
+}
+
+// jsonlRecord is a line of the jsonl dump, key and value are base64 encoded
+type jsonlRecord struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type jsonlDumpWriter struct {
+	bw  *bufio.Writer
+	enc *json.Encoder
+}
+
+func (dw *jsonlDumpWriter) WriteKVs(kvs client.KVS) error {
+	for _, kv := range kvs {
+		if err := dw.enc.Encode(jsonlRecord{Key: kv.K, Value: kv.V}); err != nil {
+			return err
+		}
+	}
+	return dw.bw.Flush()
+}
+
+func (dw *jsonlDumpWriter) Flush() error {
+	return dw.bw.Flush()
+}
+
+func (dw *jsonlDumpWriter) Close() error {
+	return dw.bw.Flush()
+}
+
+type binaryDumpWriter struct {
+	bw       *bufio.Writer
+	checksum uint64
+	buf      []byte
+}
+
+func (dw *binaryDumpWriter) write(p []byte) error {
+	dw.checksum = crc64.Update(dw.checksum, binaryDumpTable, p)
+	_, err := dw.bw.Write(p)
+	return err
+}
+
+func (dw *binaryDumpWriter) WriteKVs(kvs client.KVS) error {
+	for _, kv := range kvs {
+		dw.buf = append(dw.buf[:0], binaryFrameKV)
+		dw.buf = binary.AppendUvarint(dw.buf, uint64(len(kv.K)))
+		dw.buf = append(dw.buf, kv.K...)
+		dw.buf = binary.AppendUvarint(dw.buf, uint64(len(kv.V)))
+		dw.buf = append(dw.buf, kv.V...)
+		if err := dw.write(dw.buf); err != nil {
+			return err
+		}
+	}
+	return dw.bw.Flush()
+}
+
+func (dw *binaryDumpWriter) Flush() error {
+	return dw.bw.Flush()
+}
+
+func (dw *binaryDumpWriter) Close() error {
+	if err := dw.write([]byte{binaryFrameEnd}); err != nil {
+		return err
+	}
+	var sum [8]byte
+	binary.BigEndian.PutUint64(sum[:], dw.checksum)
+	if _, err := dw.bw.Write(sum[:]); err != nil {
+		return err
+	}
+	return dw.bw.Flush()
+}
+
+// dumpReader reads kv pairs from a dump file
+type dumpReader interface {
+	// Next returns io.EOF at the end of the dump
+	Next() (client.KV, error)
+	// Close releases the decompressor if there is, the underlying reader is
+	// not closed
+	Close()
+}
+
+// openDumpReader sniffs the compression and the format of r, and returns the
+// reader and the format
+func openDumpReader(r io.Reader) (dumpReader, string, error) {
+	br := bufio.NewReader(r)
+	var closer func()
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", err
+		}
+		br, closer = bufio.NewReader(gr), func() { gr.Close() }
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, "", err
+		}
+		br, closer = bufio.NewReader(zr), zr.Close
+	}
+
+	var dr dumpReader
+	var format string
+	head, _ := br.Peek(len(binaryDumpMagic) + 1)
+	switch {
+	case bytes.HasPrefix(head, binaryDumpMagic):
+		br.Discard(len(head))
+		if len(head) <= len(binaryDumpMagic) || head[len(binaryDumpMagic)] != binaryDumpVersion {
+			return nil, "", errors.New("invalid backup file: unsupported binary version")
+		}
+		dr, format = &binaryDumpReader{br: br}, dumpFormatBinary
+	// the jsonl backup of an empty range is an empty file
+	case len(head) == 0 || bytes.HasPrefix(head, []byte("{")):
+		dr, format = &jsonlDumpReader{dec: json.NewDecoder(br)}, dumpFormatJSONL
+	default:
+		r := csv.NewReader(br)
+		header, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil, "", errors.New("invalid backup file: empty file")
+			}
+			return nil, "", err
+		}
+		if len(header) != 2 || header[0] != "Key" || header[1] != "Value" {
+			return nil, "", fmt.Errorf("invalid backup file: header should be Key,Value, got: %v", header)
+		}
+		dr, format = &csvDumpReader{r: r}, dumpFormatCSV
+	}
+	if closer != nil {
+		dr = &closeDumpReader{dumpReader: dr, closer: closer}
+	}
+	return dr, format, nil
+}
+
+type closeDumpReader struct {
+	dumpReader
+	closer func()
+}
+
+func (dr *closeDumpReader) Close() {
+	dr.dumpReader.Close()
+	dr.closer()
+}
+
+type csvDumpReader struct {
+	r *csv.Reader
+}
+
+func (dr *csvDumpReader) Next() (client.KV, error) {
+	rec, err := dr.r.Read()
+	if err != nil {
+		return client.KV{}, err
+	}
+	line, _ := dr.r.FieldPos(0)
+	if len(rec) != 2 {
+		return client.KV{}, fmt.Errorf("invalid backup file: line %d: %v, format should be: <key>,<value>", line, rec)
+	}
+	k, err := utils.GetStringLit(rec[0])
+	if err != nil {
+		return client.KV{}, fmt.Errorf("invalid backup file: line %d: %s", line, err)
+	}
+	v, err := utils.GetStringLit(rec[1])
+	if err != nil {
+		return client.KV{}, fmt.Errorf("invalid backup file: line %d: %s", line, err)
+	}
+	return client.KV{K: k, V: v}, nil
+}
+
+func (dr *csvDumpReader) Close() {}
+
+type jsonlDumpReader struct {
+	dec  *json.Decoder
+	line int
+}
+
+func (dr *jsonlDumpReader) Next() (client.KV, error) {
+	var rec jsonlRecord
+	if err := dr.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			return client.KV{}, err
+		}
+		return client.KV{}, fmt.Errorf("invalid backup file: line %d: %s", dr.line+1, err)
+	}
+	dr.line++
+	return client.KV{K: rec.Key, V: rec.Value}, nil
+}
+
+func (dr *jsonlDumpReader) Close() {}
+
+type binaryDumpReader struct {
+	br       *bufio.Reader
+	checksum uint64
+	done     bool
+}
+
+// ReadByte and Read update the checksum with the bytes read
+func (dr *binaryDumpReader) ReadByte() (byte, error) {
+	b, err := dr.br.ReadByte()
+	if err == nil {
+		dr.checksum = crc64.Update(dr.checksum, binaryDumpTable, []byte{b})
+	}
+	return b, err
+}
+
+// binaryDumpMaxBytes is the max length of a key or a value in the binary
+// dump, which is far larger than the max raft entry of TiKV
+const binaryDumpMaxBytes = 64 << 20
+
+// readBytes reads a length-prefixed byte slice
+func (dr *binaryDumpReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(dr)
+	if err != nil {
+		return nil, errBinaryDumpTruncated
+	}
+	// the length of a corrupt file may be huge, don't allocate it
+	if n > binaryDumpMaxBytes {
+		return nil, fmt.Errorf("invalid backup file: corrupt dump, the length %d exceeds the limit %d", n, binaryDumpMaxBytes)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(dr.br, buf); err != nil {
+		return nil, errBinaryDumpTruncated
+	}
+	dr.checksum = crc64.Update(dr.checksum, binaryDumpTable, buf)
+	return buf, nil
+}
+
+var errBinaryDumpTruncated = errors.New("invalid backup file: unexpected end of the binary dump, the file may be truncated")
+
+func (dr *binaryDumpReader) Next() (client.KV, error) {
+	if dr.done {
+		return client.KV{}, io.EOF
+	}
+	typ, err := dr.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			return client.KV{}, errBinaryDumpTruncated
+		}
+		return client.KV{}, err
+	}
+	switch typ {
+	case binaryFrameKV:
+		k, err := dr.readBytes()
+		if err != nil {
+			return client.KV{}, err
+		}
+		v, err := dr.readBytes()
+		if err != nil {
+			return client.KV{}, err
+		}
+		return client.KV{K: k, V: v}, nil
+	case binaryFrameEnd:
+		var sum [8]byte
+		if _, err := io.ReadFull(dr.br, sum[:]); err != nil {
+			return client.KV{}, errBinaryDumpTruncated
+		}
+		if binary.BigEndian.Uint64(sum[:]) != dr.checksum {
+			return client.KV{}, errors.New("invalid backup file: checksum mismatch")
+		}
+		dr.done = true
+		return client.KV{}, io.EOF
+	default:
+		return client.KV{}, fmt.Errorf("invalid backup file: unknown frame type: %d", typ)
+	}
+}
+
+func (dr *binaryDumpReader) Close() {}
@@ -0,0 +1,267 @@
+package kvcmds
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/c4pt0r/tcli/client"
+)
+
+func testDumpKVs() client.KVS {
+	kvs := client.KVS{
+		{K: []byte("a"), V: []byte("1")},
+		{K: []byte("b,c"), V: []byte("\"quoted\"\n")},
+		{K: []byte{0, 0xff, '{'}, V: []byte{}},
+		{K: []byte("empty value"), V: nil},
+	}
+	for i := 0; i < 100; i++ {
+		kvs = append(kvs, client.KV{K: []byte(fmt.Sprintf("k%03d", i)), V: bytes.Repeat([]byte{byte(i)}, i)})
+	}
+	return kvs
+}
+
+// writeDump writes the kv pairs in batches of batchSize
+func writeDump(t *testing.T, format, compress string, kvs client.KVS, batchSize int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	cw, err := newCompressWriter(compress, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cw != nil {
+		w = cw
+	}
+	dw, err := newDumpWriter(format, w, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(kvs); i += batchSize {
+		end := i + batchSize
+		if end > len(kvs) {
+			end = len(kvs)
+		}
+		if err := dw.WriteKVs(kvs[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if cw != nil {
+		if err := cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// readDump reads all the kv pairs of the dump
+func readDump(data []byte) (client.KVS, string, error) {
+	dr, format, err := openDumpReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	defer dr.Close()
+	var ret client.KVS
+	for {
+		kv, err := dr.Next()
+		if err == io.EOF {
+			return ret, format, nil
+		}
+		if err != nil {
+			return ret, format, err
+		}
+		ret = append(ret, kv)
+	}
+}
+
+// sameKVs compares the kv pairs, nil and empty values are the same
+func sameKVs(a, b client.KVS) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].K, b[i].K) || !bytes.Equal(a[i].V, b[i].V) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	kvs := testDumpKVs()
+	for _, format := range []string{dumpFormatCSV, dumpFormatJSONL, dumpFormatBinary} {
+		for _, compress := range []string{"", dumpCompressGzip, dumpCompressZstd} {
+			t.Run(format+"/"+compress, func(t *testing.T) {
+				if err := checkDumpFormat(format, compress); err != nil {
+					t.Fatal(err)
+				}
+				data := writeDump(t, format, compress, kvs, 7)
+				got, gotFormat, err := readDump(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if gotFormat != format {
+					t.Fatalf("got format %s, want %s", gotFormat, format)
+				}
+				if !sameKVs(got, kvs) {
+					t.Fatalf("got %v, want %v", got, kvs)
+				}
+			})
+		}
+	}
+}
+
+// the backups of an empty range are read back in their formats
+func TestDumpEmpty(t *testing.T) {
+	for _, format := range []string{dumpFormatCSV, dumpFormatJSONL, dumpFormatBinary} {
+		for _, compress := range []string{"", dumpCompressGzip, dumpCompressZstd} {
+			got, gotFormat, err := readDump(writeDump(t, format, compress, nil, 1))
+			if err != nil {
+				t.Fatalf("%s/%s: %v", format, compress, err)
+			}
+			if gotFormat != format || len(got) != 0 {
+				t.Fatalf("%s/%s: got %v in %s", format, compress, got, gotFormat)
+			}
+		}
+	}
+}
+
+func TestDumpFormatInvalid(t *testing.T) {
+	cases := []struct {
+		format, compress string
+	}{
+		{"xml", ""},
+		{"", ""},
+		{dumpFormatCSV, "lz4"},
+	}
+	for _, tc := range cases {
+		if err := checkDumpFormat(tc.format, tc.compress); err == nil {
+			t.Errorf("checkDumpFormat(%q, %q) should fail", tc.format, tc.compress)
+		}
+	}
+	if _, err := newDumpWriter("xml", io.Discard, false, 0); err == nil {
+		t.Error("newDumpWriter(xml) should fail")
+	}
+	if _, err := newCompressWriter("lz4", io.Discard); err == nil {
+		t.Error("newCompressWriter(lz4) should fail")
+	}
+}
+
+// the binary dump is resumed with the checksum of the written frames
+func TestBinaryDumpResume(t *testing.T) {
+	kvs := testDumpKVs()
+	var buf bytes.Buffer
+	dw, err := newDumpWriter(dumpFormatBinary, &buf, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dw.WriteKVs(kvs[:10]); err != nil {
+		t.Fatal(err)
+	}
+	if err := dw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	checksum := dw.(*binaryDumpWriter).checksum
+
+	dw, err = newDumpWriter(dumpFormatBinary, &buf, true, checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dw.WriteKVs(kvs[10:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := dw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := readDump(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameKVs(got, kvs) {
+		t.Fatalf("got %v, want %v", got, kvs)
+	}
+}
+
+func TestBinaryDumpCorrupt(t *testing.T) {
+	kvs := client.KVS{{K: []byte("key"), V: []byte("value")}}
+	data := writeDump(t, dumpFormatBinary, "", kvs, 1)
+	header := len(binaryDumpMagic) + 1
+
+	hugeFrame := append([]byte{}, data[:header]...)
+	hugeFrame = append(hugeFrame, binaryFrameKV)
+	hugeFrame = binary.AppendUvarint(hugeFrame, 1<<40)
+
+	cases := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"truncated trailer", data[:len(data)-4], "truncated"},
+		{"no trailer", data[:len(data)-9], "truncated"},
+		{"truncated frame", data[:header+3], "truncated"},
+		{"checksum mismatch", append(append([]byte{}, data[:len(data)-1]...), data[len(data)-1]^1), "checksum mismatch"},
+		{"modified value", bytes.Replace(data, []byte("value"), []byte("vALue"), 1), "checksum mismatch"},
+		{"unknown frame", append(append([]byte{}, data[:header]...), 9), "unknown frame type"},
+		{"huge length", hugeFrame, "exceeds the limit"},
+		{"unsupported version", append(append([]byte{}, binaryDumpMagic...), 2), "unsupported binary version"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := readDump(tc.data)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTextDumpInvalid(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"blank lines", "\n\n", "empty file"},
+		{"csv header", "K,V\n", "header should be Key,Value"},
+		{"csv columns", "Key,Value\na,b,c\n", "line 2"},
+		{"csv hex", "Key,Value\nh'zz',b\n", "line 2"},
+		{"jsonl", "{\"key\":\"YQ==\",\"value\":\"Yg==\"}\n{\"key\":1}\n", "line 2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := readDump([]byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDumpReaderFormats(t *testing.T) {
+	cases := []struct {
+		data   string
+		format string
+		want   client.KVS
+	}{
+		{"", dumpFormatJSONL, nil},
+		{"Key,Value\n", dumpFormatCSV, nil},
+		{"Key,Value\na,h'62'\n\"c\",d\n", dumpFormatCSV, client.KVS{{K: []byte("a"), V: []byte("b")}, {K: []byte("c"), V: []byte("d")}}},
+		{"{\"key\":\"YQ==\",\"value\":\"Yg==\"}\n", dumpFormatJSONL, client.KVS{{K: []byte("a"), V: []byte("b")}}},
+	}
+	for _, tc := range cases {
+		got, format, err := readDump([]byte(tc.data))
+		if err != nil {
+			t.Fatalf("read %q: %v", tc.data, err)
+		}
+		if format != tc.format || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("read %q: got %v in %s, want %v in %s", tc.data, got, format, tc.want, tc.format)
+		}
+	}
+}
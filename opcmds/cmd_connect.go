@@ -2,10 +2,14 @@ package opcmds
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/abiosoft/ishell"
 	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
 )
 
 type ConnectCmd struct{}
@@ -15,20 +19,192 @@ var _ tcli.Cmd = ConnectCmd{}
 func (c ConnectCmd) Name() string    { return ".connect" }
 func (c ConnectCmd) Alias() []string { return []string{".c", ".conn"} }
 func (c ConnectCmd) Help() string {
-	return "connect to a tikv cluster, usage: [.connect|.conn|.c] [pd addr], example: .c 192.168.1.1:2379"
+	return "connect to a tikv cluster, usage: [.connect|.conn|.c] <pd addrs|profile> [--mode=raw|txn] [--api-version=1|2] [--keyspace=<name>]"
 }
 
 func (c ConnectCmd) LongHelp() string {
-	return c.Help()
+	s := c.Help()
+	s += `
+Usage:
+	.connect <pd addrs> [--mode=raw|txn|mem] [--api-version=1|2] [--keyspace=<name>]
+	.connect <profile> [--mode=raw|txn|mem] [--api-version=1|2] [--keyspace=<name>]
+Options:
+	--mode=<raw|txn|mem>, the client mode, default: the mode of the profile, or the current mode
+	--api-version=<1|2>, the TiKV API version, default: the API version of the profile, or of tcli
+	--keyspace=<name>, the keyspace to access, requires API V2, default: the keyspace of the
+		profile, or of tcli
+Description:
+	connect to the cluster and replace the current client, which is closed after the new client
+	is connected. the pd addrs are separated by ',', and connected with the TLS flags (--ca,
+	--cert, --key, --cert-allowed-cn) and the API flags (--api-version, --keyspace) of tcli.
+	the profiles are read from the config file (~/.tcli.toml by default), e.g.
+		[profiles.prod]
+		pd = "10.0.1.1:2379,10.0.1.2:2379"
+		mode = "txn"
+		ca = "/path/to/ca.pem"
+		cert = "/path/to/client.pem"
+		key = "/path/to/client-key.pem"
+		api-version = 2
+		keyspace = "ks1"
+	only pd, mode, the TLS settings and the API settings of the profile are used by .connect.
+	the connection can't be switched with an open transaction.
+Examples:
+	.connect 192.168.1.1:2379
+	.connect 192.168.1.1:2379,192.168.1.2:2379 --mode=raw
+	.connect 192.168.1.1:2379 --api-version=2 --keyspace=ks1
+	.connect prod
+`
+	return s
+}
+
+// connectTarget resolves the arg of .connect to the pd addrs, the mode, the
+// TLS config and the API option, the arg is either a profile name or the pd
+// addrs
+func connectTarget(arg string, opt *properties.Properties) ([]string, string, client.SecurityOption, client.APIOption, error) {
+	var (
+		addrs, mode string
+		security    client.SecurityOption
+		api         client.APIOption
+	)
+	if p, ok := utils.GetConfig().GetProfile(arg); ok {
+		addrs, mode = p.PD, p.Mode
+		if addrs == "" {
+			return nil, "", security, api, fmt.Errorf("no pd addrs in profile %s", arg)
+		}
+		security = client.SecurityOption{
+			CAPath:        p.CA,
+			CertPath:      p.Cert,
+			KeyPath:       p.Key,
+			CertAllowedCN: p.CertAllowedCN,
+		}
+		api = client.APIOption{Version: p.APIVersion, Keyspace: p.Keyspace}
+	} else {
+		addrs = arg
+		security = client.GetDefaultSecurity()
+		api = client.GetDefaultAPI()
+	}
+	if m, ok := opt.Get(tcli.ConnectOptMode); ok {
+		mode = m
+	}
+	if _, ok := opt.Get(tcli.ConnectOptAPIVersion); ok {
+		api.Version = opt.GetInt(tcli.ConnectOptAPIVersion, 0)
+		if api.Version == 0 {
+			return nil, "", security, api, fmt.Errorf("invalid api-version: %s, accepted values: [1 | 2]", opt.GetString(tcli.ConnectOptAPIVersion, ""))
+		}
+	}
+	if ks, ok := opt.Get(tcli.ConnectOptKeyspace); ok {
+		api.Keyspace = ks
+	}
+	if mode == "" {
+		switch client.GetTiKVClient().GetClientMode() {
+		case client.RAW_CLIENT:
+			mode = "raw"
+		default:
+			mode = "txn"
+		}
+	}
+	pdAddrs := utils.SplitList(addrs)
+	if len(pdAddrs) == 0 && strings.ToLower(mode) != "mem" {
+		return nil, "", security, api, errors.New("no pd addrs")
+	}
+	return pdAddrs, mode, security, api, nil
+}
+
+// newClient connects to the cluster, it can be interrupted by Ctrl-C
+func newClient(pdAddrs []string, mode string, security client.SecurityOption, api client.APIOption) (client.Client, error) {
+	ctx, stop := utils.WithInterrupt(context.TODO())
+	defer stop()
+
+	type result struct {
+		c   client.Client
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		c, err := client.NewClient(pdAddrs, mode, security, api)
+		ch <- result{c, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.c, r.err
+	case <-ctx.Done():
+		// close the client if it's connected after all
+		go func() {
+			if r := <-ch; r.err == nil {
+				r.c.Close()
+			}
+		}()
+		return nil, errors.New("connecting is interrupted")
+	}
 }
 
 func (c ConnectCmd) Handler() func(ctx context.Context) {
 	return func(ctx context.Context) {
-		ic := ctx.Value("ishell").(*ishell.Context)
-		if len(ic.Args) > 0 {
-			ic.SetPrompt(fmt.Sprintf("[%s] >>> ", ic.Args[0]))
-		} else {
-			ic.Println(ic.Cmd.HelpText())
-		}
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			if len(args) < 2 { // args[0] is the command name
+				utils.Print(c.LongHelp())
+				if names := utils.GetConfig().ProfileNames(); len(names) > 0 {
+					utils.Print("Profiles: " + strings.Join(names, ", "))
+				}
+				return nil
+			}
+			if info := client.GetTiKVClient().GetTxnInfo(); info != nil {
+				return fmt.Errorf("transaction %d is still open, commit or rollback it before switching the connection", info.StartTS)
+			}
+			pdAddrs, mode, security, api, err := connectTarget(args[1], opt)
+			if err != nil {
+				return err
+			}
+			kvClient, err := newClient(pdAddrs, mode, security, api)
+			if err != nil {
+				return err
+			}
+			client.SwitchTiKVClient(kvClient)
+			target := strings.Join(pdAddrs, ",")
+			if kvClient.GetPDClient() == nil {
+				target = "in-memory storage"
+			}
+			msg := fmt.Sprintf("Connected to %s, Cluster ID: %s, %s", target, kvClient.GetClusterID(), kvClient.GetClientMode())
+			if name := client.ClientAPI(kvClient).KeyspaceName(); name != "" {
+				msg += ", Keyspace: " + name
+			}
+			utils.Print(msg)
+			return nil
+		})
+	}
+}
+
+type DisconnectCmd struct{}
+
+var _ tcli.Cmd = DisconnectCmd{}
+
+func (c DisconnectCmd) Name() string    { return ".disconnect" }
+func (c DisconnectCmd) Alias() []string { return []string{".disconnect"} }
+func (c DisconnectCmd) Help() string {
+	return "disconnect from the current cluster, use .connect to connect again"
+}
+
+func (c DisconnectCmd) LongHelp() string {
+	return c.Help()
+}
+
+func (c DisconnectCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			if client.GetTiKVClient().GetClientMode() == client.NONE_CLIENT {
+				return client.ErrNotConnected
+			}
+			if info := client.GetTiKVClient().GetTxnInfo(); info != nil {
+				return fmt.Errorf("transaction %d is still open, commit or rollback it before disconnecting", info.StartTS)
+			}
+			client.DisconnectTiKVClient()
+			return nil
+		})
 	}
 }
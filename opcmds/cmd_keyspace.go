@@ -0,0 +1,76 @@
+package opcmds
+
+import (
+	"context"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+)
+
+type ListKeyspacesCmd struct{}
+
+var _ tcli.Cmd = ListKeyspacesCmd{}
+
+func (c ListKeyspacesCmd) Name() string    { return ".keyspaces" }
+func (c ListKeyspacesCmd) Alias() []string { return []string{".keyspaces"} }
+func (c ListKeyspacesCmd) Help() string {
+	return "list the keyspaces of the cluster"
+}
+
+func (c ListKeyspacesCmd) LongHelp() string {
+	s := c.Help()
+	s += `
+Usage:
+	.keyspaces <options>
+Options:
+	--limit=<n>, max number of keyspaces to list, default: 1000
+Description:
+	the keyspaces are loaded from the HTTP API of the PD leader (/pd/api/v2/keyspaces), which
+	requires a PD with keyspaces support.
+Examples:
+	.keyspaces
+	.keyspaces --limit=10
+`
+	return s
+}
+
+func (c ListKeyspacesCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			_, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			limit := opt.GetInt(tcli.KeyspacesOptLimit, 1000)
+
+			ctx, stop := utils.WithInterrupt(context.TODO())
+			defer stop()
+			// fetch one more keyspace to know if the list is truncated
+			fetchLimit := limit
+			if limit > 0 {
+				fetchLimit = limit + 1
+			}
+			keyspaces, err := client.GetKeyspaces(ctx, client.GetTiKVClient(), fetchLimit)
+			if err != nil {
+				return err
+			}
+			truncated := limit > 0 && len(keyspaces) > limit
+			if truncated {
+				keyspaces = keyspaces[:limit]
+			}
+			output := [][]string{client.KeyspaceInfo{}.TableTitle()}
+			for _, keyspace := range keyspaces {
+				output = append(output, keyspace.Flatten())
+			}
+			utils.PrintTable(output)
+			if truncated {
+				utils.Print("Only the first", limit, "keyspaces are listed, use --limit to list more")
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package opcmds
+
+import (
+	"context"
+	"errors"
+
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/c4pt0r/tcli/utils"
+	"github.com/magiconair/properties"
+	pd "github.com/tikv/pd/client"
+)
+
+// getPDClient returns the PD client of the current client, which is nil in mem mode
+func getPDClient() (pd.Client, error) {
+	kvClient := client.GetTiKVClient()
+	if kvClient.GetClientMode() == client.NONE_CLIENT {
+		return nil, client.ErrNotConnected
+	}
+	pdClient := kvClient.GetPDClient()
+	if pdClient == nil {
+		return nil, errors.New("regions are not supported in mem mode")
+	}
+	return pdClient, nil
+}
+
+// getStoreAddrs returns the addresses of all the stores by their IDs
+func getStoreAddrs(ctx context.Context, pdClient pd.Client) (map[uint64]string, error) {
+	stores, err := pdClient.GetAllStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[uint64]string, len(stores))
+	for _, store := range stores {
+		ret[store.GetId()] = store.GetAddress()
+	}
+	return ret, nil
+}
+
+type RegionCmd struct{}
+
+var _ tcli.Cmd = RegionCmd{}
+
+func (c RegionCmd) Name() string    { return ".region" }
+func (c RegionCmd) Alias() []string { return []string{".region"} }
+func (c RegionCmd) Help() string {
+	return "show the region which the key is in"
+}
+
+func (c RegionCmd) LongHelp() string {
+	s := c.Help()
+	s += `
+Usage:
+	.region <key>
+Description:
+	the key is in the same string literal as get, the start and end keys of the region are shown in hex string literal.
+Examples:
+	.region "a"
+	.region h'610a'
+`
+	return s
+}
+
+func (c RegionCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			if len(ic.Args) < 1 {
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			k, err := utils.GetStringLit(ic.RawArgs[1])
+			if err != nil {
+				return err
+			}
+			pdClient, err := getPDClient()
+			if err != nil {
+				return err
+			}
+			region, err := pdClient.GetRegion(context.TODO(), k)
+			if err != nil {
+				return err
+			}
+			if region == nil || region.Meta == nil {
+				return errors.New("region not found")
+			}
+			storeAddrs, err := getStoreAddrs(context.TODO(), pdClient)
+			if err != nil {
+				return err
+			}
+			info := client.NewRegionInfo(region, storeAddrs)
+			utils.PrintTable([][]string{info.TableTitle(), info.Flatten()})
+			return nil
+		})
+	}
+}
+
+type ListRegionsCmd struct{}
+
+var _ tcli.Cmd = ListRegionsCmd{}
+
+func (c ListRegionsCmd) Name() string    { return ".regions" }
+func (c ListRegionsCmd) Alias() []string { return []string{".regions"} }
+func (c ListRegionsCmd) Help() string {
+	return "list the regions in a key range"
+}
+
+func (c ListRegionsCmd) LongHelp() string {
+	s := c.Help()
+	s += `
+Usage:
+	.regions <start key> [end key] <options>
+Options:
+	--limit=<n>, max number of regions to list, default: 1000
+Description:
+	list the regions overlapping [start key, end key), no end key means no upper bound,
+	the keys are in the same string literal as get, use * as the start key to list from the first region.
+Examples:
+	.regions *
+	.regions "a" "b"
+	.regions h'7480' --limit=10
+`
+	return s
+}
+
+func (c ListRegionsCmd) Handler() func(ctx context.Context) {
+	return func(ctx context.Context) {
+		utils.OutputWithElapse(func() error {
+			ic := utils.ExtractIshellContext(ctx)
+			// parse args and options from raw args to keep the string literals
+			args, flags := utils.GetArgsAndOptionFlag(ic.RawArgs)
+			if len(args) < 2 { // args[0] is the command name
+				utils.Print(c.LongHelp())
+				return nil
+			}
+			opt := properties.NewProperties()
+			if err := utils.SetOptByString(flags, opt); err != nil {
+				return err
+			}
+			start, err := utils.GetStringLit(args[1])
+			if err != nil {
+				return err
+			}
+			if string(start) == "*" {
+				start = nil
+			}
+			var end []byte
+			if len(args) > 2 {
+				if end, err = utils.GetStringLit(args[2]); err != nil {
+					return err
+				}
+			}
+			limit := opt.GetInt(tcli.RegionsOptLimit, 1000)
+
+			pdClient, err := getPDClient()
+			if err != nil {
+				return err
+			}
+			storeAddrs, err := getStoreAddrs(context.TODO(), pdClient)
+			if err != nil {
+				return err
+			}
+			output := [][]string{client.RegionInfo{}.TableTitle()}
+			cur := start
+			for len(output)-1 < limit {
+				regions, err := pdClient.ScanRegions(context.TODO(), cur, end, client.ScanRegionsBatchSize)
+				if err != nil {
+					return err
+				}
+				if len(regions) == 0 {
+					break
+				}
+				for _, region := range regions {
+					if len(output)-1 >= limit {
+						break
+					}
+					output = append(output, client.NewRegionInfo(region, storeAddrs).Flatten())
+				}
+				cur = regions[len(regions)-1].Meta.GetEndKey()
+				// the last region
+				if len(cur) == 0 {
+					break
+				}
+			}
+			utils.PrintTable(output)
+			if len(output)-1 == limit {
+				utils.Print("Only the first", limit, "regions are listed, use --limit to list more")
+			}
+			return nil
+		})
+	}
+}
@@ -18,16 +18,22 @@ var (
 
 type queryStorage struct {
 	client client.Client
+	// read options, like --as-of
+	readOpt *properties.Properties
 }
 
-func NewQueryStorage(client client.Client) kvql.Storage {
+func NewQueryStorage(client client.Client, readOpt *properties.Properties) kvql.Storage {
+	if readOpt == nil {
+		readOpt = properties.NewProperties()
+	}
 	return &queryStorage{
-		client: client,
+		client:  client,
+		readOpt: readOpt,
 	}
 }
 
 func (s *queryStorage) Get(key []byte) ([]byte, error) {
-	kv, err := s.client.Get(context.TODO(), client.Key(key))
+	kv, err := s.client.Get(utils.ContextWithProp(context.TODO(), s.readOpt), client.Key(key))
 	if err != nil {
 		if err.Error() == "not exist" {
 			return nil, nil
@@ -81,6 +87,7 @@ type queryCursor struct {
 
 func (c *queryCursor) loadBatch() error {
 	scanOpt := properties.NewProperties()
+	scanOpt.Merge(c.storage.readOpt)
 	scanOpt.Set(tcli.ScanOptLimit, "10")
 	scanOpt.Set(tcli.ScanOptKeyOnly, "false")
 	scanOpt.Set(tcli.ScanOptCountOnly, "false")
@@ -0,0 +1,182 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/c4pt0r/kvql"
+	"github.com/c4pt0r/tcli"
+	"github.com/c4pt0r/tcli/client"
+	"github.com/magiconair/properties"
+)
+
+// newTestStorage returns the query storage of a memkv client with the kv
+// pairs k00 => v0 ... k<n-1> => v<n-1>
+func newTestStorage(t *testing.T, n int) kvql.Storage {
+	t.Helper()
+	if err := client.InitTiKVClient(nil, "mem", client.SecurityOption{}, client.APIOption{}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewQueryStorage(client.GetTiKVClient(), nil)
+	var kvs []kvql.KVPair
+	for i := 0; i < n; i++ {
+		kvs = append(kvs, kvql.NewKVPStr(fmt.Sprintf("k%02d", i), fmt.Sprintf("v%d", i)))
+	}
+	if err := s.BatchPut(kvs); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestQueryStorage(t *testing.T) {
+	s := newTestStorage(t, 3)
+
+	if v, err := s.Get([]byte("k01")); err != nil || string(v) != "v1" {
+		t.Fatalf("got %q, %v, want v1", v, err)
+	}
+	// a missing key is not an error
+	if v, err := s.Get([]byte("not exists")); err != nil || v != nil {
+		t.Fatalf("got %q, %v, want nil", v, err)
+	}
+	if err := s.Put([]byte("k01"), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.Get([]byte("k01")); string(v) != "new" {
+		t.Fatalf("got %q, want new", v)
+	}
+	if err := s.Delete([]byte("k00")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BatchDelete([][]byte{[]byte("k01"), []byte("k02")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"k00", "k01", "k02"} {
+		if v, err := s.Get([]byte(k)); err != nil || v != nil {
+			t.Fatalf("got %q, %v of deleted key %s", v, err, k)
+		}
+	}
+}
+
+// the snapshot reads are rejected in mem mode, instead of reading the latest
+func TestQueryStorageAsOf(t *testing.T) {
+	newTestStorage(t, 1)
+	readOpt := properties.NewProperties()
+	readOpt.Set(tcli.ScanOptAsOf, "1")
+	s := NewQueryStorage(client.GetTiKVClient(), readOpt)
+	if _, err := s.Get([]byte("k00")); err == nil {
+		t.Fatal("get should fail")
+	}
+	cur, err := s.Cursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cur.Next(); err == nil {
+		t.Fatal("scan should fail")
+	}
+}
+
+func TestQueryCursor(t *testing.T) {
+	// more than a batch of the cursor
+	s := newTestStorage(t, 25)
+
+	cases := []struct {
+		name  string
+		seek  string
+		first string
+		count int
+	}{
+		{"all", "", "k00", 25},
+		{"seek", "k07", "k07", 18},
+		{"seek between keys", "k07a", "k08", 17},
+		{"seek the last key", "k24", "k24", 1},
+		{"seek after the last key", "k30", "", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cur, err := s.Cursor()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cur.Seek([]byte(tc.seek)); err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for {
+				k, v, err := cur.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if k == nil {
+					break
+				}
+				var i int
+				fmt.Sscanf(string(k), "k%d", &i)
+				if want := fmt.Sprintf("v%d", i); string(v) != want {
+					t.Fatalf("got value %q of %q, want %q", v, k, want)
+				}
+				keys = append(keys, string(k))
+			}
+			if len(keys) != tc.count {
+				t.Fatalf("got %d keys %q, want %d", len(keys), keys, tc.count)
+			}
+			if tc.count > 0 && keys[0] != tc.first {
+				t.Fatalf("got first key %q, want %q", keys[0], tc.first)
+			}
+			for i := 1; i < len(keys); i++ {
+				if keys[i-1] >= keys[i] {
+					t.Fatalf("keys are not in order: %q", keys)
+				}
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := newTestStorage(t, 25)
+
+	cases := []struct {
+		query string
+		want  [][]string
+	}{
+		{"select key, value where key ^= 'k1' & key < 'k13'", [][]string{{"k10", "v10"}, {"k11", "v11"}, {"k12", "v12"}}},
+		{"select key where key in ('k03', 'k21', 'x')", [][]string{{"k03"}, {"k21"}}},
+		{"select key where key between 'k05' and 'k07'", [][]string{{"k05"}, {"k06"}, {"k07"}}},
+		{"select count(1) where key ^= 'k'", [][]string{{"25"}}},
+		{"select key where value = 'v24'", [][]string{{"k24"}}},
+		{"select key where key ^= 'k2' order by key desc limit 2", [][]string{{"k24"}, {"k23"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			plan, err := kvql.NewOptimizer(tc.query).BuildPlan(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			ectx := kvql.NewExecuteCtx()
+			for {
+				cols, err := plan.Next(ectx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ectx.Clear()
+				if cols == nil {
+					break
+				}
+				row := make([]string, len(cols))
+				for i, col := range cols {
+					switch v := col.(type) {
+					case []byte:
+						row[i] = string(v)
+					default:
+						row[i] = fmt.Sprint(v)
+					}
+				}
+				got = append(got, row)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
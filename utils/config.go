@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultProfileName is the profile used if --profile is not set
+const DefaultProfileName = "default"
+
+// Profile is a named set of settings in the config file, e.g.
+//
+//	[profiles.prod]
+//	pd = "10.0.1.1:2379,10.0.1.2:2379"
+//	mode = "txn"
+//	ca = "/path/to/ca.pem"
+//	cert = "/path/to/client.pem"
+//	key = "/path/to/client-key.pem"
+//	cert-allowed-cn = ["tikv"]
+//	api-version = 2
+//	keyspace = "ks1"
+//	output-format = "json"
+//
+//	[profiles.prod.sysvars]
+//	"sys.read_ts" = ""
+//
+//	[profiles.prod.aliases]
+//	ls = "scanp"
+type Profile struct {
+	PD   string `toml:"pd" yaml:"pd"`
+	Mode string `toml:"mode" yaml:"mode"`
+
+	// TLS, the connections are not encrypted if CA is empty
+	CA            string   `toml:"ca" yaml:"ca"`
+	Cert          string   `toml:"cert" yaml:"cert"`
+	Key           string   `toml:"key" yaml:"key"`
+	CertAllowedCN []string `toml:"cert-allowed-cn" yaml:"cert-allowed-cn"`
+
+	// API V2 and the keyspace, 0 means the default API version
+	APIVersion int    `toml:"api-version" yaml:"api-version"`
+	Keyspace   string `toml:"keyspace" yaml:"keyspace"`
+
+	OutputFormat string `toml:"output-format" yaml:"output-format"`
+	// the initial values of the system variables
+	SysVars map[string]string `toml:"sysvars" yaml:"sysvars"`
+	// alias name => command, the args of the alias are appended to the command
+	Aliases map[string]string `toml:"aliases" yaml:"aliases"`
+}
+
+// Config is the content of the config file, ~/.tcli.toml by default, the
+// file is in YAML if its extension is .yaml or .yml
+type Config struct {
+	// the history file of the interactive shell, ~/.tcli.history by default
+	HistoryFile string              `toml:"history-file" yaml:"history-file"`
+	Profiles    map[string]*Profile `toml:"profiles" yaml:"profiles"`
+}
+
+var (
+	_configMutex  sync.RWMutex
+	_globalConfig = &Config{}
+)
+
+// DefaultConfigPath returns the first existing one of ~/.tcli.toml,
+// ~/.tcli.yaml and ~/.tcli.yml, or ~/.tcli.toml if none of them exists.
+// returns an empty string if the home directory is unknown
+func DefaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range []string{".tcli.toml", ".tcli.yaml", ".tcli.yml"} {
+		path := filepath.Join(home, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(home, ".tcli.toml")
+}
+
+// LoadConfig reads the config file, a missing file is an empty config
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	default:
+		var md toml.MetaData
+		if md, err = toml.Decode(string(data), cfg); err == nil {
+			// report the typos, as yaml.UnmarshalStrict does
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				var keys []string
+				for _, k := range undecoded {
+					keys = append(keys, k.String())
+				}
+				err = errors.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+			}
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load config file %s", path)
+	}
+	return cfg, nil
+}
+
+func SetConfig(cfg *Config) {
+	_configMutex.Lock()
+	defer _configMutex.Unlock()
+	_globalConfig = cfg
+}
+
+func GetConfig() *Config {
+	_configMutex.RLock()
+	defer _configMutex.RUnlock()
+	return _globalConfig
+}
+
+// GetProfile returns the profile by its name
+func (cfg *Config) GetProfile(name string) (*Profile, bool) {
+	p, ok := cfg.Profiles[name]
+	return p, ok && p != nil
+}
+
+// ProfileNames returns the sorted names of the profiles
+func (cfg *Config) ProfileNames() []string {
+	var names []string
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HistoryPath returns the path of the history file, a leading ~ is expanded
+// to the home directory, and a relative path is in the home directory
+func (cfg *Config) HistoryPath() string {
+	path := cfg.HistoryFile
+	if path == "" {
+		path = ".tcli.history"
+	}
+	home, _ := os.UserHomeDir()
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(home, path)
+	}
+	return path
+}
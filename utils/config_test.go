@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	want := &Config{
+		HistoryFile: "/tmp/history",
+		Profiles: map[string]*Profile{
+			"prod": {
+				PD:            "10.0.1.1:2379,10.0.1.2:2379",
+				Mode:          "txn",
+				CA:            "ca.pem",
+				Cert:          "client.pem",
+				Key:           "client-key.pem",
+				CertAllowedCN: []string{"tikv"},
+				APIVersion:    2,
+				Keyspace:      "ks1",
+				OutputFormat:  "json",
+				SysVars:       map[string]string{"sys.read_ts": "1"},
+				Aliases:       map[string]string{"ls": "scanp"},
+			},
+			"dev": {PD: "127.0.0.1:2379", Mode: "raw"},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		file    string
+		content string
+		want    *Config
+		wantErr string
+	}{
+		{
+			name: "toml",
+			file: "tcli.toml",
+			content: `history-file = "/tmp/history"
+[profiles.prod]
+pd = "10.0.1.1:2379,10.0.1.2:2379"
+mode = "txn"
+ca = "ca.pem"
+cert = "client.pem"
+key = "client-key.pem"
+cert-allowed-cn = ["tikv"]
+api-version = 2
+keyspace = "ks1"
+output-format = "json"
+[profiles.prod.sysvars]
+"sys.read_ts" = "1"
+[profiles.prod.aliases]
+ls = "scanp"
+[profiles.dev]
+pd = "127.0.0.1:2379"
+mode = "raw"
+`,
+			want: want,
+		},
+		{
+			name: "yaml",
+			file: "tcli.yaml",
+			content: `history-file: /tmp/history
+profiles:
+  prod:
+    pd: 10.0.1.1:2379,10.0.1.2:2379
+    mode: txn
+    ca: ca.pem
+    cert: client.pem
+    key: client-key.pem
+    cert-allowed-cn: [tikv]
+    api-version: 2
+    keyspace: ks1
+    output-format: json
+    sysvars:
+      sys.read_ts: "1"
+    aliases:
+      ls: scanp
+  dev:
+    pd: 127.0.0.1:2379
+    mode: raw
+`,
+			want: want,
+		},
+		{
+			name:    "empty",
+			file:    "tcli.toml",
+			content: "",
+			want:    &Config{},
+		},
+		{
+			name:    "unknown key in toml",
+			file:    "tcli.toml",
+			content: "[profiles.prod]\npd_addr = \"127.0.0.1:2379\"\n",
+			wantErr: "unknown keys: profiles.prod.pd_addr",
+		},
+		{
+			name:    "unknown key in yaml",
+			file:    "tcli.yml",
+			content: "profiles:\n  prod:\n    pd_addr: 127.0.0.1:2379\n",
+			wantErr: "pd_addr",
+		},
+		{
+			name:    "invalid toml",
+			file:    "tcli.toml",
+			content: "[profiles.prod\n",
+			wantErr: "failed to load config file",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, tc.want) {
+				t.Fatalf("got %+v, want %+v", cfg, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "not-exists.toml")} {
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.Profiles) != 0 || cfg.HistoryFile != "" {
+			t.Fatalf("got %+v from %q, want an empty config", cfg, path)
+		}
+	}
+}
+
+func TestConfigProfiles(t *testing.T) {
+	cfg := &Config{Profiles: map[string]*Profile{
+		"prod":  {PD: "a"},
+		"dev":   {PD: "b"},
+		"empty": nil,
+	}}
+	if got, want := cfg.ProfileNames(), []string{"dev", "empty", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got profile names %q, want %q", got, want)
+	}
+	if p, ok := cfg.GetProfile("prod"); !ok || p.PD != "a" {
+		t.Fatalf("got profile %+v, %v", p, ok)
+	}
+	for _, name := range []string{"empty", "not-exists"} {
+		if _, ok := cfg.GetProfile(name); ok {
+			t.Fatalf("got profile %s", name)
+		}
+	}
+}
+
+func TestHistoryPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	cases := []struct {
+		file string
+		want string
+	}{
+		{"", filepath.Join(home, ".tcli.history")},
+		{"~/h", filepath.Join(home, "h")},
+		{"h", filepath.Join(home, "h")},
+		{"/tmp/h", "/tmp/h"},
+	}
+	for _, tc := range cases {
+		cfg := &Config{HistoryFile: tc.file}
+		if got := cfg.HistoryPath(); got != tc.want {
+			t.Errorf("HistoryPath of %q = %q, want %q", tc.file, got, tc.want)
+		}
+	}
+}
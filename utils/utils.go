@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
 	"time"
@@ -22,6 +23,27 @@ var (
 	propertiesKey = "property"
 )
 
+var (
+	// error returned by the last command which runs with OutputWithElapse
+	_lastCmdErr = atomic.NewError(nil)
+	// false if the commands are read from -e, -f or piped stdin
+	_interactive = atomic.NewBool(true)
+)
+
+// ErrConfirmRequired is returned if a command asks for confirmation in
+// non-interactive mode
+var ErrConfirmRequired = errors.New("confirmation is required in non-interactive mode, pass --yes to run the command")
+
+// SetInteractive sets whether the commands are typed in the interactive shell
+func SetInteractive(interactive bool) {
+	_interactive.Store(interactive)
+}
+
+// IsInteractive returns true if the commands are typed in the interactive shell
+func IsInteractive() bool {
+	return _interactive.Load()
+}
+
 func PrintTable(data [][]string) {
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader(data[0])
@@ -42,9 +64,22 @@ func PrintTableNoWrap(data [][]string) {
 	table.Render()
 }
 
+// WithInterrupt returns a context which is canceled on Ctrl-C, the returned
+// stop function restores the default behavior of Ctrl-C
+func WithInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
+
 func OutputWithElapse(f func() error) error {
 	tt := time.Now()
 	err := f()
+	_lastCmdErr.Store(err)
+	if IsQuiet() {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31mError: %s\033[0m\n", err)
+		}
+		return err
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\033[31mError: %s\033[0m\nElapse: %d ms\n", err, time.Since(tt)/time.Millisecond)
 	} else {
@@ -53,6 +88,54 @@ func OutputWithElapse(f func() error) error {
 	return err
 }
 
+// LastCmdError returns the error of the last command, nil if it succeeded
+func LastCmdError() error {
+	return _lastCmdErr.Load()
+}
+
+// ResetLastCmdError clears the error of the last command
+func ResetLastCmdError() {
+	_lastCmdErr.Store(nil)
+}
+
+// SplitStatements splits a script into statements, statements are separated by
+// ';' or newline, separators inside quotes are ignored.
+// Example:
+// `put a "1;2"; get a` => ['put a "1;2"', 'get a']
+func SplitStatements(script string) []string {
+	var stmts []string
+	var buf strings.Builder
+	var quote rune
+	escaped := false
+	flush := func() {
+		stmt := strings.TrimSpace(buf.String())
+		if len(stmt) > 0 {
+			stmts = append(stmts, stmt)
+		}
+		buf.Reset()
+	}
+	for _, r := range script {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ';' || r == '\n':
+			flush()
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	flush()
+	return stmts
+}
+
 func Hexstr2bytes(hexStr string) ([]byte, error) {
 	return hex.DecodeString(hexStr)
 }
@@ -117,11 +200,21 @@ func GetStringLit(raw string) ([]byte, error) {
 	return []byte(raw), nil
 }
 
+// GetStringLitOpt returns the value of option key parsed as a string literal,
+// returns nil if the option is not set
+func GetStringLitOpt(props *properties.Properties, key string) ([]byte, error) {
+	raw, ok := props.Get(key)
+	if !ok || len(raw) == 0 {
+		return nil, nil
+	}
+	return GetStringLit(raw)
+}
+
 func SetOptByString(ss []string, props *properties.Properties) error {
 	for _, flag := range ss {
 		if strings.HasPrefix(flag, "--") {
 			flag = flag[2:]
-			parts := strings.Split(flag, "=")
+			parts := strings.SplitN(flag, "=", 2)
 
 			switch len(parts) {
 			case 1:
@@ -196,7 +289,7 @@ func ContextWithProp(ctx context.Context, p *properties.Properties) context.Cont
 }
 
 func PropFromContext(ctx context.Context) *properties.Properties {
-	prop := ctx.Value(propertiesKey).(*properties.Properties)
+	prop, _ := ctx.Value(propertiesKey).(*properties.Properties)
 	if prop == nil {
 		return properties.NewProperties()
 	}
@@ -205,7 +298,7 @@ func PropFromContext(ctx context.Context) *properties.Properties {
 
 type ProgressReader struct {
 	totalSz int64
-	readSz  *atomic.Int32
+	readSz  *atomic.Int64
 	rdr     io.Reader
 	err     atomic.Value
 }
@@ -213,7 +306,7 @@ type ProgressReader struct {
 func NewProgressReader(r io.Reader, total int64) *ProgressReader {
 	return &ProgressReader{
 		totalSz: total,
-		readSz:  atomic.NewInt32(0),
+		readSz:  atomic.NewInt64(0),
 		err:     atomic.Value{},
 		rdr:     r,
 	}
@@ -225,7 +318,7 @@ func (pr *ProgressReader) Read(b []byte) (int, error) {
 		pr.err.Store(err)
 		return n, err
 	}
-	pr.readSz.Add(int32(n))
+	pr.readSz.Add(int64(n))
 	return n, err
 }
 
@@ -233,6 +326,11 @@ func (pr *ProgressReader) GetProgress() float64 {
 	return float64(pr.readSz.Load()) / float64(pr.totalSz)
 }
 
+// ReadSize returns the number of bytes read
+func (pr *ProgressReader) ReadSize() int64 {
+	return pr.readSz.Load()
+}
+
 func (pr *ProgressReader) Error() error {
 	v := pr.err.Load()
 	if v != nil {
@@ -292,10 +390,31 @@ func HasForceYes(ctx context.Context) bool {
 	return false
 }
 
+// Confirm returns true if the command is run with --yes, or the user answers
+// yes to msg. returns ErrConfirmRequired in non-interactive mode without --yes
+func Confirm(ctx context.Context, msg string) (bool, error) {
+	if HasForceYes(ctx) {
+		return true, nil
+	}
+	if !IsInteractive() {
+		return false, ErrConfirmRequired
+	}
+	return AskYesNo(msg, "no") == 1, nil
+}
+
 func Print(a ...interface{}) {
 	fmt.Println(a...)
 }
 
+// PrintProgress prints the progress of a long running command to stderr, so
+// it's not mixed with the output, nothing is printed in quiet mode
+func PrintProgress(a ...interface{}) {
+	if IsQuiet() {
+		return
+	}
+	fmt.Fprintln(os.Stderr, a...)
+}
+
 func ExtractIshellContext(ctx context.Context) *ishell.Context {
 	ic := ctx.Value("ishell").(*ishell.Context)
 	return ic
@@ -308,3 +427,28 @@ func NextKey(k []byte) []byte {
 	copy(buf, k)
 	return buf
 }
+
+// PrefixEnd returns the smallest key which is greater than all keys with
+// the prefix, returns nil (no upper bound) if there is no such key.
+func PrefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// SplitList splits the comma-separated list, e.g. the PD addresses, the spaces
+// around the items and the empty items are dropped
+func SplitList(s string) []string {
+	var ret []string
+	for _, addr := range strings.Split(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			ret = append(ret, addr)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		script string
+		want   []string
+	}{
+		{"", nil},
+		{"  ;; \n ", nil},
+		{"get a", []string{"get a"}},
+		{"put a b; get a", []string{"put a b", "get a"}},
+		{"put a b\nget a\n", []string{"put a b", "get a"}},
+		{`put a "1;2"; get a`, []string{`put a "1;2"`, "get a"}},
+		{`put a '1;2'; get a`, []string{`put a '1;2'`, "get a"}},
+		{"put a \"1\n2\"", []string{"put a \"1\n2\""}},
+		{`put a "it's"; get a`, []string{`put a "it's"`, "get a"}},
+		{`put a "\";"; get a`, []string{`put a "\";"`, "get a"}},
+		{`put a 1\;2; get a`, []string{`put a 1\;2`, "get a"}},
+		{`put a h'3b'; get a`, []string{`put a h'3b'`, "get a"}},
+		// an unterminated quote takes the rest of the script
+		{`put a "1; get a`, []string{`put a "1; get a`}},
+	}
+	for _, tc := range cases {
+		if got := SplitStatements(tc.script); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("SplitStatements(%q) = %q, want %q", tc.script, got, tc.want)
+		}
+	}
+}
+
+func TestGetArgsAndOptionFlag(t *testing.T) {
+	cases := []struct {
+		raw       []string
+		wantArgs  []string
+		wantFlags []string
+	}{
+		{[]string{}, []string{}, []string{}},
+		{[]string{"scan", "a"}, []string{"scan", "a"}, []string{}},
+		{[]string{"scan", "a", "--limit=10", "--key-only"}, []string{"scan", "a"}, []string{"--limit=10", "--key-only"}},
+		{[]string{"scan", "--limit=10", "a"}, []string{"scan", "a"}, []string{"--limit=10"}},
+	}
+	for _, tc := range cases {
+		args, flags := GetArgsAndOptionFlag(tc.raw)
+		if !reflect.DeepEqual(args, tc.wantArgs) || !reflect.DeepEqual(flags, tc.wantFlags) {
+			t.Errorf("GetArgsAndOptionFlag(%q) = %q, %q, want %q, %q", tc.raw, args, flags, tc.wantArgs, tc.wantFlags)
+		}
+	}
+}
+
+func TestGetStringLit(t *testing.T) {
+	VarSet("v", []byte("var value"))
+
+	cases := []struct {
+		raw     string
+		want    []byte
+		wantErr bool
+	}{
+		{"abc", []byte("abc"), false},
+		{`"abc"`, []byte("abc"), false},
+		{`'a b'`, []byte("a b"), false},
+		{`h'616263'`, []byte("abc"), false},
+		{`h"00ff"`, []byte{0, 0xff}, false},
+		{`h'zz'`, nil, true},
+		{"$v", []byte("var value"), false},
+		{"$not_exists", nil, true},
+		{"--limit=1", nil, true},
+	}
+	for _, tc := range cases {
+		got, err := GetStringLit(tc.raw)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("GetStringLit(%q) returns error %v, want error: %v", tc.raw, err, tc.wantErr)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("GetStringLit(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestNextKeyAndPrefixEnd(t *testing.T) {
+	if got := NextKey([]byte("a")); !bytes.Equal(got, []byte("a\x00")) {
+		t.Errorf("NextKey(a) = %q", got)
+	}
+	if got := NextKey(nil); !bytes.Equal(got, []byte{0}) {
+		t.Errorf("NextKey(nil) = %q", got)
+	}
+
+	cases := []struct {
+		prefix []byte
+		want   []byte
+	}{
+		{[]byte("a"), []byte("b")},
+		{[]byte("ab"), []byte("ac")},
+		{[]byte{'a', 0xff}, []byte("b")},
+		{[]byte{0xff, 0xff}, nil},
+		{nil, nil},
+	}
+	for _, tc := range cases {
+		if got := PrefixEnd(tc.prefix); !bytes.Equal(got, tc.want) {
+			t.Errorf("PrefixEnd(%q) = %q, want %q", tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	cases := []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{" , ,", nil},
+		{"127.0.0.1:2379", []string{"127.0.0.1:2379"}},
+		{"a:2379, b:2379,,c:2379 ", []string{"a:2379", "b:2379", "c:2379"}},
+	}
+	for _, tc := range cases {
+		if got := SplitList(tc.s); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("SplitList(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
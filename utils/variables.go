@@ -8,6 +8,10 @@ import (
 
 var (
 	SysVarPrintFormatKey string = "sys.printfmt"
+	SysVarQuietKey       string = "sys.quiet"
+	// TSO or RFC3339 time, reads in txn mode see the snapshot at this
+	// timestamp, empty means the latest version
+	SysVarReadTSKey string = "sys.read_ts"
 )
 
 var (
@@ -21,6 +25,8 @@ var (
 	_globalSysVariables = make(map[string]string)
 	_builtinSysVars     = [][]string{
 		{SysVarPrintFormatKey, "table"},
+		{SysVarQuietKey, "false"},
+		{SysVarReadTSKey, ""},
 	}
 )
 
@@ -91,3 +97,10 @@ func PrintSysVaribles() {
 		PrintTable(data)
 	}
 }
+
+// IsQuiet returns true if the Success/Elapse banners and record counts
+// should not be printed, so stdout/stderr only carry data and errors
+func IsQuiet() bool {
+	val, ok := SysVarGet(SysVarQuietKey)
+	return ok && val == "true"
+}